@@ -1,20 +1,33 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/zalando/go-keyring"
+	"github.com/tempestdx/cli/internal/oauth"
+	"github.com/tempestdx/cli/internal/profile"
+	"github.com/tempestdx/cli/internal/secret"
+	appapi "github.com/tempestdx/openapi/app"
 	"golang.org/x/term"
 )
 
 var (
-	authWithToken bool
+	authWithToken   bool
+	authWeb         bool
+	authReveal      bool
+	authEncrypt     bool
+	authMigrateFrom string
+	authMigrateTo   string
 
 	authCmd = &cobra.Command{
 		Use:   "auth <command> [flags]",
@@ -25,52 +38,172 @@ var (
 	authShowCmd = &cobra.Command{
 		Use:   "show",
 		Short: "Show the current authentication token.",
+		Long:  `Show the current authentication token. Tokens are masked unless --reveal is set.`,
 		RunE:  authShowRunE,
 	}
 
+	authStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Check the current authentication token against the Tempest API.",
+		Long: `Check the current authentication token against the Tempest API.
+
+Reports where the token is loaded from, validates it with an authenticated API call,
+and exits with a non-zero status if the token is missing or invalid.`,
+		Args:         cobra.NoArgs,
+		RunE:         authStatusRunE,
+		SilenceUsage: true,
+	}
+
 	authLoginCmd = &cobra.Command{
 		Use:   "login [flags]",
 		Short: "Login to the Tempest API.",
 		Long: `Login to the Tempest API. This command will prompt for an API Key.
-The key will be stored securely in the OS native keychain, or disk of the keychain is not available.`,
+The key will be stored securely in the OS native keychain, or on disk if the keychain is not available.
+Tokens stored on disk are readable only by the current user, and can be encrypted with a passphrase
+using --encrypt. The passphrase is read from TEMPEST_TOKEN_PASSPHRASE, or prompted for.
+
+Set TEMPEST_TOKEN_STORE, or the token_store setting of the profile, to 'keyring' or 'file' to
+choose where the key is stored.
+
+Set TEMPEST_CREDENTIAL_HELPER, or the credential_helper setting of the profile, to store the
+key with an external command instead. The command is run with 'get', 'store' or 'erase'
+appended, and exchanges key=value lines on stdin and stdout like a git credential helper.
+
+Use --web to login in the browser instead. The CLI receives a short-lived access token,
+which is refreshed automatically.
+
+Use --profile to store the key for a named profile, and --api-endpoint to set the
+endpoint used by that profile:
+
+  tempest auth login --profile staging --api-endpoint https://staging.example.com/api/v1`,
 		RunE: authLoginRunE,
 	}
 
 	authLogoutCmd = &cobra.Command{
 		Use:   "logout",
 		Short: "Logout from the Tempest API.",
-		Long:  `Logout from the Tempest API. This command will remove the API Key from the OS native keychain.`,
+		Long:  `Logout from the Tempest API. This command will remove the API Key from the OS native keychain and the token file.`,
 		RunE:  authLogoutRunE,
 	}
+
+	authMigrateCmd = &cobra.Command{
+		Use:   "migrate --from <store> --to <store>",
+		Short: "Move the stored token between token stores.",
+		Long: `Move the stored token between token stores. Accepted stores: 'keyring', 'file'.
+The profile then uses the new store. Tokens moved to a file are encrypted with a passphrase,
+like with 'tempest auth login --encrypt'.
+
+  tempest auth migrate --from keyring --to file`,
+		Args: cobra.NoArgs,
+		RunE: authMigrateRunE,
+	}
 )
 
 func init() {
 	rootCmd.AddCommand(authCmd)
 	authCmd.AddCommand(authShowCmd)
+	authCmd.AddCommand(authStatusCmd)
 	authCmd.AddCommand(authLoginCmd)
 	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authMigrateCmd)
+
+	authShowCmd.Flags().BoolVar(&authReveal, "reveal", false, "Show the raw tokens instead of masking them.")
 
 	authLoginCmd.Flags().BoolVarP(&authWithToken, "with-token", "t", false, "Authenticate with a token passed on stdin.")
+	authLoginCmd.Flags().BoolVar(&authEncrypt, "encrypt", false, "Store the token in a passphrase encrypted file instead of the keychain.")
+	authLoginCmd.Flags().BoolVarP(&authWeb, "web", "w", false, "Login in the browser with a one-time code.")
+	authLoginCmd.MarkFlagsMutuallyExclusive("web", "with-token")
+
+	authMigrateCmd.Flags().StringVar(&authMigrateFrom, "from", "", "(REQUIRED) The token store to move the token from.")
+	authMigrateCmd.Flags().StringVar(&authMigrateTo, "to", "", "(REQUIRED) The token store to move the token to.")
+	if err := authMigrateCmd.MarkFlagRequired("from"); err != nil {
+		panic(err)
+	}
+	if err := authMigrateCmd.MarkFlagRequired("to"); err != nil {
+		panic(err)
+	}
 }
 
 func authShowRunE(cmd *cobra.Command, args []string) error {
 	token, err := tokenStore.Get()
-	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+	if err != nil && !errors.Is(err, secret.ErrNotFound) {
 		return err
 	}
 
-	cmd.Println(fmt.Sprintf(`TEMPEST_TOKEN="%s"`, os.Getenv("TEMPEST_TOKEN")))
+	cmd.Println(fmt.Sprintf("Profile: %s", profileName))
+	cmd.Println(fmt.Sprintf("API endpoint: %s", apiEndpoint))
+	cmd.Println(fmt.Sprintf(`TEMPEST_TOKEN="%s"`, maskToken(os.Getenv("TEMPEST_TOKEN"))))
 	cmd.Println(fmt.Sprintf(`TEMPEST_TOKEN_FILE="%s"`, os.Getenv("TEMPEST_TOKEN_FILE")))
-	cmd.Println(fmt.Sprintf("Token from keychain: %s", token))
+	cmd.Println(fmt.Sprintf("Token from token store: %s", maskToken(token)))
 
 	return nil
 }
 
+func maskToken(token string) string {
+	if authReveal {
+		return token
+	}
+
+	return secret.Mask(token)
+}
+
+func authStatusRunE(cmd *cobra.Command, args []string) error {
+	cmd.Printf("Profile:      %s\n", profileName)
+	cmd.Printf("API endpoint: %s\n", apiEndpoint)
+
+	token, source, err := resolveTempestToken()
+	cmd.Printf("Token source: %s\n", source)
+	if err != nil {
+		return fmt.Errorf("load token: %w\n Try: %s", err, loginCommand())
+	}
+	cmd.Printf("Token:        %s\n", secret.Mask(token))
+
+	tempestClient, err := appapi.NewClientWithResponses(
+		apiEndpoint,
+		appapi.WithHTTPClient(&http.Client{
+			Timeout:   10 * time.Second,
+			Transport: tempestTransport(token),
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("create client: %w", err)
+	}
+
+	// Listing projects is the cheapest authenticated call, and it tells us
+	// which organization the token belongs to. The API has no endpoint
+	// returning the identity of the caller.
+	res, err := tempestClient.PostProjectsListWithResponse(context.TODO(), appapi.PostProjectsListJSONRequestBody{})
+	if err != nil {
+		return fmt.Errorf("validate token: %w", err)
+	}
+
+	switch res.StatusCode() {
+	case http.StatusOK:
+		cmd.Println("Status:       ✅ valid")
+		if res.JSON200 != nil && len(res.JSON200.Projects) > 0 {
+			cmd.Printf("Organization: %s\n", res.JSON200.Projects[0].OrganizationId)
+		} else {
+			cmd.Println("Organization: unknown, the organization is only known from its projects and it has none")
+		}
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		cmd.Println("Status:       ❌ invalid")
+		return fmt.Errorf("token was rejected by the Tempest API: %s\n Try: %s", res.Status(), loginCommand())
+	default:
+		return fmt.Errorf("validate token: unexpected response: %s", res.Status())
+	}
+}
+
 func authLoginRunE(cmd *cobra.Command, args []string) error {
 	var input string
 	var err error
 
-	if authWithToken {
+	if authWeb {
+		input, err = authLoginWeb(cmd)
+		if err != nil {
+			return err
+		}
+	} else if authWithToken {
 		input, err = readInputFromStdin(cmd)
 		if err != nil {
 			return err
@@ -105,7 +238,173 @@ func authLoginRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return tokenStore.Set(input)
+	store := tokenStore
+	storeKind := ""
+	if authEncrypt {
+		f, err := fileTokenStore(profileName)
+		if err != nil {
+			return err
+		}
+
+		f.Passphrase, err = readNewPassphrase()
+		if err != nil {
+			return err
+		}
+
+		store = f
+		// Otherwise a token left in the keyring would shadow this one.
+		storeKind = tokenStoreFile
+	}
+
+	if err := store.Set(input); err != nil {
+		return err
+	}
+
+	return saveProfile(cmd, storeKind)
+}
+
+const oauthClientID = "tempest-cli"
+
+// oauthConfig returns the OAuth configuration for the active API endpoint.
+func oauthConfig() *oauth.Config {
+	base := strings.TrimSuffix(apiEndpoint, "/")
+
+	return &oauth.Config{
+		ClientID:      oauthClientID,
+		DeviceAuthURL: base + "/oauth/device/code",
+		TokenURL:      base + "/oauth/token",
+		HTTPClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// authLoginWeb runs the device authorization flow, and returns the encoded
+// token to store.
+func authLoginWeb(cmd *cobra.Command) (string, error) {
+	ctx := context.Background()
+	cfg := oauthConfig()
+
+	code, err := cfg.RequestDeviceCode(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	uri := code.VerificationURI
+	if code.VerificationURIComplete != "" {
+		uri = code.VerificationURIComplete
+	}
+
+	cmd.Printf("First copy your one-time code: %s\n", code.UserCode)
+	cmd.Printf("Then open %s in your browser to authorize the Tempest CLI.\n", uri)
+	if err := openBrowser(uri); err != nil {
+		cmd.PrintErrf("Could not open the browser: %v\n", err)
+	}
+	cmd.Println("Waiting for authorization...")
+
+	token, err := cfg.PollToken(ctx, code)
+	if err != nil {
+		return "", err
+	}
+
+	cmd.Println("✅ Authorized.")
+
+	return token.Encode()
+}
+
+// refreshStoredToken refreshes a token obtained with `auth login --web`, and
+// stores the new token.
+func refreshStoredToken(ctx context.Context, token *oauth.Token) (*oauth.Token, error) {
+	refreshed, err := oauthConfig().Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n Try: %s --web", err, loginCommand())
+	}
+
+	encoded, err := refreshed.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tokenStore.Set(encoded); err != nil {
+		return nil, fmt.Errorf("store refreshed token: %w", err)
+	}
+
+	return refreshed, nil
+}
+
+func openBrowser(url string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.Command("open", url)
+	case "windows":
+		c = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		c = exec.Command("xdg-open", url)
+	}
+
+	return c.Start()
+}
+
+// readNewPassphrase returns TEMPEST_TOKEN_PASSPHRASE, or prompts for a new
+// passphrase twice when running in a terminal.
+func readNewPassphrase() (string, error) {
+	if p := os.Getenv("TEMPEST_TOKEN_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", errors.New("no passphrase provided\n Try: TEMPEST_TOKEN_PASSPHRASE=... tempest auth login --encrypt")
+	}
+
+	fmt.Print("New token passphrase: ")
+	first, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Print("Confirm token passphrase: ")
+	second, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	if string(first) != string(second) {
+		return "", errors.New("passphrases do not match")
+	}
+
+	if len(first) == 0 {
+		return "", errors.New("passphrase cannot be empty")
+	}
+
+	return string(first), nil
+}
+
+// saveProfile records the active profile in the profiles file, along with the
+// API endpoint if one was set explicitly, and the kind of token store when not
+// empty.
+func saveProfile(cmd *cobra.Command, storeKind string) error {
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	p, ok := profiles.Profiles[profileName]
+	if !ok {
+		p = &profile.Profile{}
+		profiles.Profiles[profileName] = p
+	}
+
+	// TEMPEST_API_ENDPOINT only applies to the current shell, so it is not
+	// persisted.
+	if cmd.Flags().Changed("api-endpoint") {
+		p.APIEndpoint = apiEndpoint
+	}
+	if storeKind != "" {
+		p.TokenStore = storeKind
+	}
+
+	return profile.Write(profiles)
 }
 
 func readInputFromStdin(cmd *cobra.Command) (string, error) {
@@ -128,6 +427,58 @@ func readInputFromStdin(cmd *cobra.Command) (string, error) {
 }
 
 func authLogoutRunE(cmd *cobra.Command, args []string) error {
-	cmd.Println("Removing stored token from the keychain, if it exists.")
+	cmd.Printf("Removing stored token for profile %s from the token store, if it exists.\n", profileName)
 	return tokenStore.Delete()
 }
+
+func authMigrateRunE(cmd *cobra.Command, args []string) error {
+	for _, kind := range []string{authMigrateFrom, authMigrateTo} {
+		if kind != tokenStoreKeyring && kind != tokenStoreFile {
+			return fmt.Errorf("invalid token store %q. Accepted values: '%s', '%s'", kind, tokenStoreKeyring, tokenStoreFile)
+		}
+	}
+
+	if authMigrateFrom == authMigrateTo {
+		return errors.New("--from and --to must be different token stores")
+	}
+
+	from, err := newTokenStore(profileName, authMigrateFrom)
+	if err != nil {
+		return err
+	}
+
+	to, err := newTokenStore(profileName, authMigrateTo)
+	if err != nil {
+		return err
+	}
+
+	token, err := from.Get()
+	if err != nil {
+		return fmt.Errorf("read token from %s: %w", authMigrateFrom, err)
+	}
+
+	if f, ok := to.(*secret.File); ok {
+		f.Passphrase, err = readNewPassphrase()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := to.Set(token); err != nil {
+		return fmt.Errorf("write token to %s: %w", authMigrateTo, err)
+	}
+
+	// Otherwise the profile would keep reading the store the token is
+	// removed from.
+	if err := saveProfile(cmd, authMigrateTo); err != nil {
+		return err
+	}
+
+	if err := from.Delete(); err != nil {
+		return fmt.Errorf("remove token from %s: %w", authMigrateFrom, err)
+	}
+
+	cmd.Printf("Moved token for profile %s from %s to %s.\n", profileName, authMigrateFrom, authMigrateTo)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/profile"
+	"github.com/tempestdx/cli/internal/secret"
+	"github.com/zalando/go-keyring"
+)
+
+// runRoot runs the root command with the given arguments, as the tempest
+// binary would.
+func runRoot(t *testing.T, args ...string) {
+	t.Helper()
+
+	profileName = ""
+	rootCmd.SetArgs(args)
+	_, err := rootCmd.ExecuteC()
+	require.NoError(t, err)
+}
+
+func TestAuthMigrate(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TEMPEST_PROFILE", "")
+	t.Setenv("TEMPEST_TOKEN_STORE", "")
+	t.Setenv("TEMPEST_CREDENTIAL_HELPER", "")
+	t.Setenv("TEMPEST_TOKEN_PASSPHRASE", "hunter2")
+
+	// The profile of auth login --encrypt.
+	f, err := fileTokenStore(profile.DefaultName)
+	require.NoError(t, err)
+	f.Passphrase = "hunter2"
+	require.NoError(t, f.Set("tok_123"))
+	require.NoError(t, profile.Write(&profile.Config{
+		Profiles: map[string]*profile.Profile{profile.DefaultName: {TokenStore: tokenStoreFile}},
+	}))
+
+	// reload loads the profile again, and returns its token.
+	reload := func() string {
+		t.Helper()
+
+		profileName = ""
+		require.NoError(t, loadProfile(rootCmd, nil))
+		token, err := tokenStore.Get()
+		require.NoError(t, err)
+		return token
+	}
+
+	runRoot(t, "auth", "migrate", "--from", tokenStoreFile, "--to", tokenStoreKeyring)
+	assert.Equal(t, "tok_123", reload())
+	assert.IsType(t, &secret.Keyring{}, tokenStore)
+
+	_, err = f.Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	runRoot(t, "auth", "migrate", "--from", tokenStoreKeyring, "--to", tokenStoreFile)
+	assert.Equal(t, "tok_123", reload())
+	require.IsType(t, &secret.File{}, tokenStore)
+	assert.True(t, tokenStore.(*secret.File).Encrypted())
+
+	_, err = keyringTokenStore(profile.DefaultName).Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+}
@@ -0,0 +1,427 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/bench"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/state"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var (
+	benchType        string
+	benchOperation   string
+	benchAction      string
+	benchInput       string
+	benchExternalID  string
+	benchConcurrency int
+	benchRate        float64
+	benchDuration    time.Duration
+	benchRequests    int
+	benchTimeout     time.Duration
+	benchOutput      string
+	benchAllowCreate bool
+
+	benchCmd = &cobra.Command{
+		Use:   "bench <app-id>:<app-version>",
+		Short: "Measure the throughput and latency of an app under load.",
+		Long: `The bench command starts the app, and sends it concurrent requests of an
+operation or action for --duration, or until --requests were sent. It reports
+the throughput, the latency percentiles, and the number of requests by Connect
+code.
+
+The strings of --input and --external-id are templates, executed for each
+request with text/template:
+
+  {{.Seq}}      the number of the request, from 1
+  {{.Worker}}   the number of the worker sending the request, from 0
+  {{rand N}}    a random integer between 0 and N-1
+  {{uuid}}      a random UUID
+
+For example: --input '{"name": "bench-{{.Seq}}"}' creates resources with
+distinct names. The create operation requires --allow-create: the resources
+created by the benchmark are not deleted, but recorded like the ones of app
+test, and deleted with 'tempest app test cleanup'.
+
+List requests fetch the first page of resources.`,
+		Args:          cobra.ExactArgs(1),
+		RunE:          benchRunE,
+		SilenceErrors: true,
+	}
+)
+
+func init() {
+	appCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().StringVarP(&benchType, "type", "t", "", "(REQUIRED) The resource type to benchmark.")
+	benchCmd.Flags().StringVarP(&benchOperation, "operation", "o", "", "The operation to benchmark. Accepted values: 'create', 'update', 'delete', 'list', 'read', 'healthcheck'.")
+	benchCmd.Flags().StringVar(&benchAction, "action", "", "The name of the action to benchmark, instead of an operation.")
+	benchCmd.Flags().StringVarP(&benchInput, "input", "i", "", "The input template of create and update operations, and actions. JSON formatted input, @file.json, @file.yaml, or - to read from stdin.")
+	benchCmd.Flags().StringVarP(&benchExternalID, "external-id", "e", "", "The external ID template of read, update and delete operations, and actions.")
+	benchCmd.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 10, "The number of requests in flight at most.")
+	benchCmd.Flags().Float64Var(&benchRate, "rate", 0, "The number of requests started per second at most. 0 sends requests as fast as the app responds.")
+	benchCmd.Flags().DurationVarP(&benchDuration, "duration", "d", 10*time.Second, "How long to send requests for.")
+	benchCmd.Flags().IntVarP(&benchRequests, "requests", "n", 0, "Stop after that many requests, even before --duration elapsed. 0 means no limit.")
+	benchCmd.Flags().DurationVar(&benchTimeout, "timeout", 10*time.Second, "The timeout of each request.")
+	benchCmd.Flags().StringVar(&benchOutput, "output", "", "Print the result as a document instead of text. Accepted values: 'json', 'yaml'.")
+	benchCmd.Flags().BoolVar(&benchAllowCreate, "allow-create", false, "Allow benchmarking the create operation, which creates a resource with each request.")
+
+	addRequestFlags(benchCmd.Flags())
+}
+
+// benchResult is the document printed by --output.
+type benchResult struct {
+	Operation   string  `json:"operation,omitempty"`
+	Action      string  `json:"action,omitempty"`
+	Type        string  `json:"type"`
+	Concurrency int     `json:"concurrency"`
+	Rate        float64 `json:"rate,omitempty"`
+	*bench.Result
+}
+
+func benchRunE(cmd *cobra.Command, args []string) error {
+	id, version, err := splitAppVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	if benchType == "" {
+		return errors.New("type is required")
+	}
+
+	switch {
+	case benchOperation != "" && benchAction != "":
+		return errors.New("--operation and --action cannot be used together")
+	case benchOperation == "" && benchAction == "":
+		return errors.New("operation is required. Accepted values: create, update, delete, list, read, healthcheck, or --action")
+	}
+
+	switch benchOperation {
+	case "create":
+		if !benchAllowCreate {
+			return errors.New("the create operation creates a resource with each request, use --allow-create to benchmark it")
+		}
+	case "", "list", "healthcheck":
+	case "read", "update", "delete":
+		if benchExternalID == "" {
+			return fmt.Errorf("external ID (--external-id) is required for the %s operation", benchOperation)
+		}
+	default:
+		return fmt.Errorf("invalid --operation %q. Accepted values: create, update, delete, list, read, healthcheck", benchOperation)
+	}
+
+	if benchConcurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+	if benchRate < 0 {
+		return errors.New("--rate must not be negative")
+	}
+	if benchRate > float64(time.Second) {
+		return fmt.Errorf("--rate must be at most %d requests per second", int64(time.Second))
+	}
+	if benchRequests < 0 {
+		return errors.New("--requests must not be negative")
+	}
+	if benchDuration <= 0 {
+		return errors.New("--duration must be positive")
+	}
+
+	switch benchOutput {
+	case "", outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid --output %q. Accepted values: %s, %s", benchOutput, outputJSON, outputYAML)
+	}
+
+	input := map[string]any{}
+	if benchInput != "" {
+		input, err = readInput(cmd.InOrStdin(), benchInput)
+		if err != nil {
+			return fmt.Errorf("invalid input: %w", err)
+		}
+	}
+	inputTemplate, err := bench.NewTemplate(input)
+	if err != nil {
+		return fmt.Errorf("--input: %w", err)
+	}
+
+	// Check the external ID template before starting the app.
+	if _, err := bench.ExecuteString(benchExternalID, bench.TemplateData{}); err != nil {
+		return fmt.Errorf("--external-id: %w", err)
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	appVersion := cfg.LookupAppByVersion(id, version)
+	if appVersion == nil {
+		return fmt.Errorf("app version %s:%s not found in config", id, version)
+	}
+
+	st, err := state.Load(state.Path(cfgDir, id, version))
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+
+	if !appPreserveBuildDir {
+		err := generateBuildDir(cfg, cfgDir, id, version)
+		if err != nil {
+			return fmt.Errorf("generate build dir: %w", err)
+		}
+	}
+
+	// Keep a connection per worker open, rather than opening a new one for
+	// most requests.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = benchConcurrency
+
+	// The logs of thousands of requests would only slow down the app.
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion,
+		runner.WithOutput(io.Discard, io.Discard),
+		runner.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		return fmt.Errorf("start app: %w", err)
+	}
+	defer cancel()
+
+	des, err := runner.Client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		return fmt.Errorf("reach private app: %w", err)
+	}
+
+	var rd *appv1.ResourceDefinition
+	types := make([]string, 0, len(des.Msg.ResourceDefinitions))
+	for _, r := range des.Msg.ResourceDefinitions {
+		types = append(types, r.Type)
+		if r.Type == benchType {
+			rd = r
+		}
+	}
+	if rd == nil {
+		slices.Sort(types)
+		return fmt.Errorf("type %s not found in app. Available types: %s", benchType, strings.Join(types, ", "))
+	}
+
+	if err := checkBenchSupported(rd); err != nil {
+		return err
+	}
+
+	// The created resources are recorded once the benchmark is over.
+	var mu sync.Mutex
+	var created []string
+	do := benchRequest(runner.Client, inputTemplate, metadata, ev, func(externalID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, externalID)
+	})
+
+	description := benchOperation
+	if benchAction != "" {
+		description = "action " + benchAction
+	}
+	if benchOutput == "" {
+		limit := "no rate limit"
+		if benchRate > 0 {
+			limit = fmt.Sprintf("%g requests/s", benchRate)
+		}
+		cmd.Printf("Benchmarking %s of %s for %s: %d workers, %s\n", description, benchType, benchDuration, benchConcurrency, limit)
+	}
+
+	// Interrupting the benchmark reports the requests sent so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result := bench.Run(ctx, bench.Options{
+		Concurrency: benchConcurrency,
+		Rate:        benchRate,
+		Duration:    benchDuration,
+		Requests:    benchRequests,
+		Timeout:     benchTimeout,
+		Do:          do,
+	})
+
+	if len(created) > 0 {
+		defer trackBenchResources(cmd, st, args[0], created)
+	}
+
+	if benchOutput != "" {
+		return printDocument(cmd.OutOrStdout(), benchOutput, &benchResult{
+			Operation:   benchOperation,
+			Action:      benchAction,
+			Type:        benchType,
+			Concurrency: benchConcurrency,
+			Rate:        benchRate,
+			Result:      result,
+		})
+	}
+
+	printBenchResult(cmd, result)
+
+	return nil
+}
+
+// checkBenchSupported checks that the resource definition supports the
+// benchmarked operation or action.
+func checkBenchSupported(rd *appv1.ResourceDefinition) error {
+	if benchAction != "" {
+		if !slices.ContainsFunc(rd.Actions, func(a *appv1.ActionDefinition) bool { return a.Name == benchAction }) {
+			return fmt.Errorf("action %s not found for type %s", benchAction, rd.Type)
+		}
+		return nil
+	}
+
+	supported := map[string]bool{
+		"create":      rd.CreateSupported,
+		"read":        rd.ReadSupported,
+		"update":      rd.UpdateSupported,
+		"delete":      rd.DeleteSupported,
+		"list":        rd.ListSupported,
+		"healthcheck": rd.HealthcheckSupported,
+	}
+	if !supported[benchOperation] {
+		return fmt.Errorf("operation %s not supported for type %s", benchOperation, rd.Type)
+	}
+
+	return nil
+}
+
+// trackBenchResources records the resources created by the benchmark in the
+// state, for app test cleanup. Failing to write the state does not fail the
+// benchmark.
+func trackBenchResources(cmd *cobra.Command, st *state.State, app string, externalIDs []string) {
+	// Apps may return the same resource for several requests.
+	slices.Sort(externalIDs)
+	externalIDs = slices.Compact(externalIDs)
+	for _, id := range externalIDs {
+		// Without an alias, adding a resource cannot fail.
+		_ = st.Add(benchType, id, "")
+	}
+
+	if err := st.Save(); err != nil {
+		cmd.Printf("⚠️  Failed to record the created resources in the state %s: %s\n", st.Path(), err)
+		return
+	}
+
+	cmd.Printf("Created resources recorded in the state: %d. Delete them with: tempest app test cleanup %s\n", len(externalIDs), app)
+}
+
+// benchRequest returns the function sending a request of the benchmarked
+// operation or action. created is called with the external ID of each created
+// resource.
+func benchRequest(client appv1connect.AppServiceClient, inputTemplate *bench.Template, metadata *appv1.Metadata, ev []*appv1.EnvironmentVariable, created func(externalID string)) func(ctx context.Context, worker, seq int) error {
+	operations := map[string]appv1.ResourceOperation{
+		"create": appv1.ResourceOperation_RESOURCE_OPERATION_CREATE,
+		"read":   appv1.ResourceOperation_RESOURCE_OPERATION_READ,
+		"update": appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE,
+		"delete": appv1.ResourceOperation_RESOURCE_OPERATION_DELETE,
+	}
+
+	return func(ctx context.Context, worker, seq int) error {
+		data := bench.TemplateData{Seq: seq, Worker: worker}
+
+		externalID, err := bench.ExecuteString(benchExternalID, data)
+		if err != nil {
+			return err
+		}
+		resource := &appv1.Resource{
+			Type:       benchType,
+			ExternalId: externalID,
+		}
+
+		var input *structpb.Struct
+		if benchAction != "" || benchOperation == "create" || benchOperation == "update" {
+			m, err := inputTemplate.Execute(data)
+			if err != nil {
+				return err
+			}
+			input, err = structpb.NewStruct(m)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case benchAction != "":
+			_, err = client.ExecuteResourceAction(ctx, connect.NewRequest(&appv1.ExecuteResourceActionRequest{
+				Resource:             resource,
+				Action:               benchAction,
+				Input:                input,
+				Metadata:             metadata,
+				EnvironmentVariables: ev,
+			}))
+		case benchOperation == "list":
+			_, err = client.ListResources(ctx, connect.NewRequest(&appv1.ListResourcesRequest{
+				Resource: resource,
+				Metadata: metadata,
+			}))
+		case benchOperation == "healthcheck":
+			_, err = client.HealthCheck(ctx, connect.NewRequest(&appv1.HealthCheckRequest{
+				Type: benchType,
+			}))
+		default:
+			var res *connect.Response[appv1.ExecuteResourceOperationResponse]
+			res, err = client.ExecuteResourceOperation(ctx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+				Operation:            operations[benchOperation],
+				Resource:             resource,
+				Input:                input,
+				Metadata:             metadata,
+				EnvironmentVariables: ev,
+			}))
+			if err == nil && benchOperation == "create" && res.Msg.GetResource().GetExternalId() != "" {
+				created(res.Msg.GetResource().GetExternalId())
+			}
+		}
+
+		return err
+	}
+}
+
+// printBenchResult prints the throughput, the latency percentiles and the
+// requests by code.
+func printBenchResult(cmd *cobra.Command, r *bench.Result) {
+	cmd.Printf("\nRequests:    %d in %.1fs (%.2f/s)\n", r.Requests, r.DurationMS/1000, r.Throughput)
+	cmd.Printf("Succeeded:   %d\n", r.Succeeded)
+	cmd.Printf("Failed:      %d\n", r.Failed)
+
+	if r.Requests > 0 {
+		l := r.Latency
+		cmd.Printf("\nLatency (ms)\n  min %.2f  mean %.2f  p50 %.2f  p90 %.2f  p99 %.2f  max %.2f\n", l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max)
+	}
+
+	cmd.Println("\nCodes")
+	for _, code := range slices.Sorted(maps.Keys(r.Codes)) {
+		cmd.Printf("  %-20s %d\n", code, r.Codes[code])
+		if msg, ok := r.Errors[code]; ok {
+			cmd.Printf("    e.g. %s\n", msg)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/state"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+)
+
+var (
+	cleanupType   string
+	cleanupDryRun bool
+
+	cleanupCmd = &cobra.Command{
+		Use:   "cleanup <app-id>:<app-version>",
+		Short: "Delete the resources created by the tests of an app.",
+		Long: `The cleanup command deletes the resources recorded in the state file of the app
+version by 'tempest app test', with the delete operation of the app. The most
+recently created resources are deleted first.
+
+Deleted resources, and the ones the app reports as not found, are removed from
+the state. Resources which could not be deleted, or whose type does not support
+delete, are kept.`,
+		Args:          cobra.ExactArgs(1),
+		RunE:          cleanupRunE,
+		SilenceErrors: true,
+	}
+)
+
+func init() {
+	testCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().StringVarP(&cleanupType, "type", "t", "", "Only delete the resources of this type.")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "Print the resources which would be deleted, without deleting them.")
+
+	addRequestFlags(cleanupCmd.Flags())
+}
+
+func cleanupRunE(cmd *cobra.Command, args []string) error {
+	id, version, err := splitAppVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	appVersion := cfg.LookupAppByVersion(id, version)
+	if appVersion == nil {
+		return fmt.Errorf("app version %s:%s not found in config", id, version)
+	}
+
+	st, err := state.Load(state.Path(cfgDir, id, version))
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+
+	// The most recent resources may depend on the older ones.
+	var resources []*state.Resource
+	for _, r := range slices.Backward(st.Resources) {
+		if cleanupType == "" || r.Type == cleanupType {
+			resources = append(resources, r)
+		}
+	}
+
+	if len(resources) == 0 {
+		cmd.Printf("No resources recorded for %s.\n", args[0])
+		return nil
+	}
+
+	if cleanupDryRun {
+		cmd.Println("Resources which would be deleted:")
+		for _, r := range resources {
+			cmd.Printf("  - %s\n", resourceLabel(r))
+		}
+		return nil
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	if !appPreserveBuildDir {
+		err := generateBuildDir(cfg, cfgDir, id, version)
+		if err != nil {
+			return fmt.Errorf("generate build dir: %w", err)
+		}
+	}
+
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion)
+	if err != nil {
+		return fmt.Errorf("start app: %w", err)
+	}
+	defer cancel()
+
+	des, err := runner.Client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		return fmt.Errorf("reach private app: %w", err)
+	}
+
+	definitions := make(map[string]*appv1.ResourceDefinition)
+	for _, rd := range des.Msg.ResourceDefinitions {
+		definitions[rd.Type] = rd
+	}
+
+	var failed int
+	for _, r := range resources {
+		rd, ok := definitions[r.Type]
+		switch {
+		case !ok:
+			cmd.Printf("⚠️  %s: the type is not in the app anymore, kept.\n", resourceLabel(r))
+			continue
+		case !rd.DeleteSupported:
+			cmd.Printf("⚠️  %s: the type does not support delete, kept.\n", resourceLabel(r))
+			continue
+		}
+
+		_, err := runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+			Operation: appv1.ResourceOperation_RESOURCE_OPERATION_DELETE,
+			Resource: &appv1.Resource{
+				Type:       r.Type,
+				ExternalId: r.ExternalID,
+			},
+			Metadata:             metadata,
+			EnvironmentVariables: ev,
+		}))
+		switch {
+		case connect.CodeOf(err) == connect.CodeNotFound:
+			cmd.Printf("✅ %s: already deleted.\n", resourceLabel(r))
+		case err != nil:
+			cmd.Printf("❌ %s: %s\n", resourceLabel(r), err)
+			failed++
+			continue
+		default:
+			cmd.Printf("✅ %s: deleted.\n", resourceLabel(r))
+		}
+
+		st.Remove(r.Type, r.ExternalID)
+		if err := st.Save(); err != nil {
+			return fmt.Errorf("save state: %w", err)
+		}
+	}
+
+	if failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d resources could not be deleted. They are kept in %s", failed, st.Path())
+	}
+
+	return nil
+}
+
+// resourceLabel describes a resource of the state, such as
+// "database 1234 (mydb)".
+func resourceLabel(r *state.Resource) string {
+	label := r.Type + " " + r.ExternalID
+	if r.Alias != "" {
+		label += " (" + r.Alias + ")"
+	}
+
+	return label
+}
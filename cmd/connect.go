@@ -13,7 +13,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/config"
 	"github.com/tempestdx/cli/internal/runner"
-	"github.com/tempestdx/cli/internal/secret"
 	appapi "github.com/tempestdx/openapi/app"
 	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
 	appsdk "github.com/tempestdx/sdk-go/app"
@@ -39,7 +38,10 @@ func connectRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	cfg, cfgDir, err := config.ReadConfig()
 	if err != nil {
@@ -89,7 +91,7 @@ func connectRunE(cmd *cobra.Command, args []string) error {
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
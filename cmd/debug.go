@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/runner"
+)
+
+var (
+	debugListen   string
+	debugContinue bool
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug <app-id>:<app-version>",
+	Short: "Run an app under the delve debugger.",
+	Long: `The debug command builds the app without optimizations, and runs it under a
+headless delve server which VS Code, GoLand or dlv connect attach to. Delve
+must be installed: go install github.com/go-delve/delve/cmd/dlv@latest
+
+The app waits for a debugger to attach and continue it, unless --continue is
+set. The operations are then run like with 'tempest app shell', or with the
+--suite like 'tempest app test', and stop at the breakpoints. The reports,
+snapshots and cassettes of app test are only used with --suite.`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          debugRunE,
+	SilenceErrors: true,
+}
+
+func init() {
+	appCmd.AddCommand(debugCmd)
+
+	debugCmd.Flags().StringVar(&debugListen, "listen", "127.0.0.1:2345", "The address the delve server listens on.")
+	debugCmd.Flags().BoolVar(&debugContinue, "continue", false, "Run the app at once, instead of waiting for a debugger to attach and continue it.")
+	debugCmd.Flags().StringVar(&testSuite, "suite", "", "Path to a YAML test suite to run, as accepted by app test, instead of starting a shell.")
+	addRequestFlags(debugCmd.Flags())
+	addSuiteFlags(debugCmd.Flags())
+}
+
+// vscodeAttach is a launch configuration of VS Code attaching to delve.
+type vscodeAttach struct {
+	Name           string           `json:"name"`
+	Type           string           `json:"type"`
+	Request        string           `json:"request"`
+	Mode           string           `json:"mode"`
+	Host           string           `json:"host"`
+	Port           int              `json:"port"`
+	SubstitutePath []substitutePath `json:"substitutePath"`
+}
+
+type substitutePath struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func debugRunE(cmd *cobra.Command, args []string) error {
+	if _, _, err := splitAppVersion(args[0]); err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(debugListen)
+	if err != nil {
+		return fmt.Errorf("--listen: %w", err)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("--listen: invalid port %q", port)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	// Fail before building the app when delve is missing.
+	if _, err := runner.LookDebugger(); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	// The app is built from the build directory, which links to the apps: the
+	// debuggers map the files of the apps to the ones the app was built with.
+	attach := vscodeAttach{
+		Name:    "Attach to " + args[0],
+		Type:    "go",
+		Request: "attach",
+		Mode:    "remote",
+		Host:    host,
+		Port:    portNumber,
+		SubstitutePath: []substitutePath{{
+			From: filepath.Join(cfgDir, "apps"),
+			To:   filepath.Join(cfgDir, cfg.BuildDir, "apps"),
+		}},
+	}
+	b, err := json.MarshalIndent(attach, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("🐞 Running %s under delve, listening on %s.\n\n", args[0], debugListen)
+	cmd.Printf("VS Code, add to the configurations of .vscode/launch.json:\n  %s\n\n", b)
+	cmd.Printf("GoLand: run a Go Remote configuration with host %s and port %d.\n", host, portNumber)
+	cmd.Printf("dlv: dlv connect %s\n\n", net.JoinHostPort(host, port))
+	if !debugContinue {
+		cmd.Println("The app waits for a debugger to attach and continue it.")
+	}
+
+	debugger := runner.WithDebugger(runner.Debugger{Addr: debugListen, Continue: debugContinue})
+	if testSuite != "" {
+		return runTest(cmd, args, debugger)
+	}
+
+	return runShell(cmd, args, debugger)
+}
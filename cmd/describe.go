@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -13,20 +14,33 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/config"
 	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/schema"
 	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // describeCmd represents the describe command.
 var describeCmd = &cobra.Command{
 	Use:   "describe <app_id:app_version>",
 	Short: "View capabilities of your Tempest App",
-	Long:  `View the resources supported and operations supported by your Tempest Private App`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  describeApp,
+	Long: `View the resources supported and operations supported by your Tempest Private App.
+
+Use --output to print the description as a document: json and yaml print the
+full Describe response of the app, with its schemas, links, lifecycle stages
+and actions, and markdown documents the resource types.`,
+	Args: cobra.ExactArgs(1),
+	RunE: describeApp,
 }
 
+var describeOutput string
+
+// outputMarkdown is the markdown document format of app describe.
+const outputMarkdown = "markdown"
+
 func init() {
 	appCmd.AddCommand(describeCmd)
+
+	describeCmd.Flags().StringVar(&describeOutput, "output", "", "Print the description as a document instead of text. Accepted values: 'json', 'yaml', 'markdown'.")
 }
 
 func splitAppVersion(appIDVersion string) (string, string, error) {
@@ -44,6 +58,12 @@ func describeApp(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	switch describeOutput {
+	case "", outputJSON, outputYAML, outputMarkdown:
+	default:
+		return fmt.Errorf("invalid --output %q. Accepted values: %s, %s, %s", describeOutput, outputJSON, outputYAML, outputMarkdown)
+	}
+
 	cfg, cfgDir, err := config.ReadConfig()
 	if err != nil {
 		return fmt.Errorf("read config: %w", err)
@@ -62,7 +82,7 @@ func describeApp(cmd *cobra.Command, args []string) error {
 	}
 
 	// Start the app runner
-	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion)
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion, outputRunnerOptions(cmd, describeOutput)...)
 	if err != nil {
 		return fmt.Errorf("start app: %w", err)
 	}
@@ -73,6 +93,18 @@ func describeApp(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("reach private app: %w", err)
 	}
 
+	switch describeOutput {
+	case outputJSON, outputYAML:
+		doc, err := protoDocument(res.Msg)
+		if err != nil {
+			return err
+		}
+		return printDocument(cmd.OutOrStdout(), describeOutput, doc)
+	case outputMarkdown:
+		_, err := fmt.Fprint(cmd.OutOrStdout(), describeMarkdown(res.Msg, id, appVersion))
+		return err
+	}
+
 	cmd.Println(`Tempest App Description
 -----------------------`)
 
@@ -133,6 +165,117 @@ Location: %s
 	return s.String()
 }
 
+// describeMarkdown documents the resource types of an app in markdown.
+func describeMarkdown(res *appv1.DescribeResponse, appID string, version *config.AppVersion) string {
+	s := strings.Builder{}
+
+	fmt.Fprintf(&s, "# %s:%s\n", appID, version.Version)
+
+	for _, r := range res.GetResourceDefinitions() {
+		fmt.Fprintf(&s, "\n## %s\n\n", r.DisplayName)
+		fmt.Fprintf(&s, "Type: `%s`\n", r.Type)
+		if r.LifecycleStage != appv1.LifecycleStage_LIFECYCLE_STAGE_UNSPECIFIED {
+			fmt.Fprintf(&s, "\nLifecycle stage: %s\n", enumName(r.LifecycleStage.String(), "LIFECYCLE_STAGE_"))
+		}
+		if r.Description != "" {
+			fmt.Fprintf(&s, "\n%s\n", r.Description)
+		}
+
+		s.WriteString("\n| Operation | Supported |\n| --- | --- |\n")
+		for _, op := range []struct {
+			name      string
+			supported bool
+		}{
+			{"Create", r.CreateSupported},
+			{"Read", r.ReadSupported},
+			{"Update", r.UpdateSupported},
+			{"Delete", r.DeleteSupported},
+			{"List", r.ListSupported},
+			{"Health check", r.HealthcheckSupported},
+		} {
+			fmt.Fprintf(&s, "| %s | %s |\n", op.name, boolToCheckmark(op.supported))
+		}
+
+		if len(r.Links) > 0 {
+			s.WriteString("\n### Links\n\n")
+			for _, link := range r.Links {
+				fmt.Fprintf(&s, "- [%s](%s) (%s)\n", link.Title, link.Url, enumName(link.Type.String(), "LINK_TYPE_"))
+			}
+		}
+
+		writeSchemaMarkdown(&s, "### Properties", r.PropertiesSchema)
+		if r.CreateSupported {
+			writeSchemaMarkdown(&s, "### Create input", r.CreateInputSchema)
+		}
+		if r.UpdateSupported {
+			writeSchemaMarkdown(&s, "### Update input", r.UpdateInputSchema)
+		}
+
+		if len(r.Actions) > 0 {
+			s.WriteString("\n### Actions\n")
+			for _, a := range r.Actions {
+				fmt.Fprintf(&s, "\n#### %s\n\n", a.DisplayName)
+				fmt.Fprintf(&s, "Name: `%s`\n", a.Name)
+				if a.Description != "" {
+					fmt.Fprintf(&s, "\n%s\n", a.Description)
+				}
+				writeSchemaMarkdown(&s, "**Input**", a.InputSchema)
+				writeSchemaMarkdown(&s, "**Output**", a.OutputSchema)
+			}
+		}
+
+		if r.InstructionsMarkdown != "" {
+			s.WriteString("\n### Instructions\n\n")
+			s.WriteString(strings.TrimSpace(r.InstructionsMarkdown))
+			s.WriteString("\n")
+		}
+	}
+
+	return s.String()
+}
+
+// writeSchemaMarkdown writes the fields of an object schema as a table under
+// the title. Schemas which cannot be compiled are written as they are.
+func writeSchemaMarkdown(s *strings.Builder, title string, st *structpb.Struct) {
+	raw := st.AsMap()
+	if len(raw) == 0 {
+		return
+	}
+
+	fmt.Fprintf(s, "\n%s\n\n", title)
+
+	sch, err := schema.New(raw)
+	if err != nil || len(sch.Fields()) == 0 {
+		b, _ := json.MarshalIndent(raw, "", "  ")
+		fmt.Fprintf(s, "```json\n%s\n```\n", b)
+		return
+	}
+
+	s.WriteString("| Field | Type | Required | Description |\n| --- | --- | --- | --- |\n")
+	for _, f := range sch.Fields() {
+		description := f.Description
+		if description == "" {
+			description = f.Title
+		}
+		required := ""
+		if f.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(s, "| `%s` | %s | %s | %s |\n", f.Name, markdownCell(f.Type), required, markdownCell(description))
+	}
+}
+
+// markdownCell escapes a value for a cell of a markdown table.
+func markdownCell(v string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ").Replace(v)
+}
+
+// enumName returns the lower case name of an enum value, without the prefix
+// of its type.
+func enumName(name, prefix string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, prefix), "_", " "))
+}
+
 func boolToCheckmark(b bool) string {
 	if b {
 		return "✅"
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/fuzz"
+	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/schema"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+)
+
+var (
+	fuzzType       string
+	fuzzOperation  string
+	fuzzExternalID string
+	fuzzRuns       int
+	fuzzSeed       uint64
+	fuzzTimeout    time.Duration
+	fuzzShrinks    int
+	fuzzKeep       bool
+	fuzzOutput     string
+
+	fuzzCmd = &cobra.Command{
+		Use:   "fuzz <app-id>:<app-version>",
+		Short: "Run an operation of an app with generated inputs.",
+		Long: `The fuzz command starts the app, and runs the create or update operation of a
+resource type with inputs generated from its input schema: boundary lengths and
+values, patterns, enum values, missing optional properties and unusual unicode
+strings.
+
+An input fails when the app panics, returns an internal error, does not respond
+within --timeout, or returns properties which do not match the properties
+schema. Inputs the app rejects as invalid are expected. Each failing input is
+shrunk to a smaller input failing the same way.
+
+Runs are reproducible: the seed is printed, and running again with --seed
+generates the same inputs. Resources created by the runs are deleted at the
+end, unless --keep is set.`,
+		Args:          cobra.ExactArgs(1),
+		RunE:          fuzzRunE,
+		SilenceErrors: true,
+	}
+)
+
+func init() {
+	appCmd.AddCommand(fuzzCmd)
+
+	fuzzCmd.Flags().StringVarP(&fuzzType, "type", "t", "", "The resource type to fuzz.")
+	fuzzCmd.Flags().StringVarP(&fuzzOperation, "operation", "o", "create", "The operation to fuzz. Accepted values: 'create', 'update'.")
+	fuzzCmd.Flags().StringVar(&fuzzExternalID, "external-id", "", "The external ID of the resource to update. Required with --operation update.")
+	fuzzCmd.Flags().IntVar(&fuzzRuns, "runs", 100, "The number of inputs to run the operation with.")
+	fuzzCmd.Flags().Uint64Var(&fuzzSeed, "seed", 0, "The seed of the generated inputs. If not specified, a random one is used.")
+	fuzzCmd.Flags().DurationVar(&fuzzTimeout, "timeout", 10*time.Second, "How long to wait for the app to respond to each run.")
+	fuzzCmd.Flags().IntVar(&fuzzShrinks, "shrinks", 1000, "The maximum number of runs spent shrinking each failing input.")
+	fuzzCmd.Flags().BoolVar(&fuzzKeep, "keep", false, "Keep the resources created by the runs, instead of deleting them.")
+	fuzzCmd.Flags().StringVar(&fuzzOutput, "output", "", "Print the result as a document instead of text. Accepted values: 'json', 'yaml'.")
+
+	addRequestFlags(fuzzCmd.Flags())
+}
+
+func fuzzRunE(cmd *cobra.Command, args []string) error {
+	id, version, err := splitAppVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	var operation appv1.ResourceOperation
+	switch fuzzOperation {
+	case "create":
+		operation = appv1.ResourceOperation_RESOURCE_OPERATION_CREATE
+	case "update":
+		operation = appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE
+		if fuzzExternalID == "" {
+			return fmt.Errorf("--external-id is required with --operation update")
+		}
+	default:
+		return fmt.Errorf("invalid --operation %q. Accepted values: create, update", fuzzOperation)
+	}
+
+	if fuzzRuns < 1 {
+		return fmt.Errorf("--runs must be at least 1")
+	}
+
+	switch fuzzOutput {
+	case "", outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid --output %q. Accepted values: %s, %s", fuzzOutput, outputJSON, outputYAML)
+	}
+
+	seed := fuzzSeed
+	if !cmd.Flags().Changed("seed") {
+		seed = rand.Uint64()
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	appVersion := cfg.LookupAppByVersion(id, version)
+	if appVersion == nil {
+		return fmt.Errorf("app version %s:%s not found in config", id, version)
+	}
+
+	if !appPreserveBuildDir {
+		err := generateBuildDir(cfg, cfgDir, id, version)
+		if err != nil {
+			return fmt.Errorf("generate build dir: %w", err)
+		}
+	}
+
+	// The app logs of thousands of runs would bury the failures, only the
+	// panics they contain are kept.
+	detector := &fuzz.PanicDetector{}
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion, runner.WithOutput(io.Discard, detector))
+	if err != nil {
+		return fmt.Errorf("start app: %w", err)
+	}
+	defer cancel()
+
+	des, err := runner.Client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		return fmt.Errorf("reach private app: %w", err)
+	}
+
+	var rd *appv1.ResourceDefinition
+	types := make([]string, 0, len(des.Msg.ResourceDefinitions))
+	for _, r := range des.Msg.ResourceDefinitions {
+		types = append(types, r.Type)
+		if r.Type == fuzzType {
+			rd = r
+		}
+	}
+	slices.Sort(types)
+
+	if fuzzType == "" {
+		return fmt.Errorf("type is required. Available types: %s", strings.Join(types, ", "))
+	}
+	if rd == nil {
+		return fmt.Errorf("type %s not found in app. Available types: %s", fuzzType, strings.Join(types, ", "))
+	}
+
+	supported, inputSchema := rd.CreateSupported, rd.CreateInputSchema
+	if operation == appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE {
+		supported, inputSchema = rd.UpdateSupported, rd.UpdateInputSchema
+	}
+	if !supported {
+		return fmt.Errorf("operation %s not supported for type %s", fuzzOperation, fuzzType)
+	}
+
+	var raw map[string]any
+	if inputSchema != nil {
+		raw = inputSchema.AsMap()
+	}
+	input, err := schema.New(raw)
+	if err != nil {
+		return fmt.Errorf("%s input schema of %s: %w", fuzzOperation, fuzzType, err)
+	}
+
+	propertiesSchema, err := propertiesSchemaFor(rd)
+	if err != nil {
+		return err
+	}
+
+	opts := fuzz.Options{
+		Operation:        operation,
+		Type:             fuzzType,
+		ExternalID:       fuzzExternalID,
+		InputSchema:      input,
+		PropertiesSchema: propertiesSchema,
+		Metadata:         metadata,
+		Env:              ev,
+		Runs:             fuzzRuns,
+		Seed:             seed,
+		Timeout:          fuzzTimeout,
+		Shrinks:          fuzzShrinks,
+		Delete:           !fuzzKeep && rd.DeleteSupported,
+		Panics:           detector.Take,
+	}
+	if fuzzOutput == "" {
+		if !fuzzKeep && !rd.DeleteSupported {
+			cmd.Printf("⚠️  %s does not support delete, the created resources will be kept.\n", fuzzType)
+		}
+		cmd.Printf("Fuzzing %s of %s with seed %d\n", fuzzOperation, fuzzType, seed)
+		opts.Progress = func(run int, f *fuzz.Failure) {
+			if f != nil {
+				cmd.Printf("❌ run %d: %s: %s\n", run, f.Kind, f.Message)
+			} else if run%100 == 0 {
+				cmd.Printf("   %d/%d runs\n", run, fuzzRuns)
+			}
+		}
+	}
+
+	result := fuzz.Run(context.TODO(), runner.Client, opts)
+
+	if fuzzOutput != "" {
+		// Always print a list, even without failures.
+		if result.Failures == nil {
+			result.Failures = []*fuzz.Failure{}
+		}
+		if err := printDocument(cmd.OutOrStdout(), fuzzOutput, result); err != nil {
+			return err
+		}
+	} else if err := printFuzzResult(cmd, result, !opts.Delete); err != nil {
+		return err
+	}
+
+	if len(result.Failures) > 0 {
+		// The failures explain themselves, the usage would only add noise.
+		cmd.SilenceUsage = true
+		return fmt.Errorf("fuzzing failed: %s. Reproduce with --seed %d", plural(len(result.Failures), "failure"), seed)
+	}
+
+	return nil
+}
+
+// printFuzzResult prints the failures with their original and shrunk inputs,
+// and the number of accepted, rejected and failed runs.
+func printFuzzResult(cmd *cobra.Command, result *fuzz.Result, kept bool) error {
+	for i, f := range result.Failures {
+		cmd.Printf("\nFailure %d (run %d): %s\n  %s\n", i+1, f.Run, f.Kind, f.Message)
+
+		shrunk, err := json.Marshal(f.Shrunk)
+		if err != nil {
+			return fmt.Errorf("marshal input: %w", err)
+		}
+		cmd.Printf("  Shrunk input: %s\n", shrunk)
+
+		if !reflect.DeepEqual(f.Input, f.Shrunk) {
+			input, err := json.Marshal(f.Input)
+			if err != nil {
+				return fmt.Errorf("marshal input: %w", err)
+			}
+			cmd.Printf("  Input:        %s\n", input)
+		}
+	}
+
+	if len(result.Created) > 0 {
+		if kept {
+			cmd.Printf("\nCreated resources: %s\n", strings.Join(result.Created, ", "))
+		} else {
+			cmd.Printf("\n⚠️  Failed to delete the created resources: %s\n", strings.Join(result.Created, ", "))
+		}
+	}
+
+	cmd.Printf("\n%d runs: %d accepted, %d rejected as invalid, %d failed\n", result.Runs, result.Accepted, result.Rejected, result.Failed)
+	if len(result.Failures) == 0 {
+		cmd.Println("✅ No failures.")
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/profile"
+)
+
+var (
+	profileTokenStore       string
+	profileCredentialHelper string
+	profileProjectID        string
+
+	profileCmd = &cobra.Command{
+		Use:   "profile <command> [flags]",
+		Short: "Manage configuration profiles.",
+		Long: `Manage configuration profiles. Each profile has its own API endpoint, stored token and
+default project ID, which allows switching between Tempest environments and accounts
+without logging in again.
+
+The profile is selected with the --profile flag, the TEMPEST_PROFILE environment variable,
+or the current profile set with "tempest profile use", in that order.`,
+	}
+
+	profileListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all profiles.",
+		Args:  cobra.NoArgs,
+		RunE:  profileListRunE,
+	}
+
+	profileUseCmd = &cobra.Command{
+		Use:   "use <profile>",
+		Short: "Set the current profile.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  profileUseRunE,
+	}
+
+	profileSetCmd = &cobra.Command{
+		Use:   "set <profile> [flags]",
+		Short: "Create or update a profile.",
+		Long: `Create or update a profile. Settings that are not passed as flags are left unchanged.
+
+  tempest profile set staging --api-endpoint https://staging.example.com/api/v1
+  tempest profile set ci --token-store file
+  tempest profile set corp --credential-helper "vault-tempest-helper"
+  tempest profile set staging --project-id my-staging-project`,
+		Args: cobra.ExactArgs(1),
+		RunE: profileSetRunE,
+	}
+
+	profileDeleteCmd = &cobra.Command{
+		Use:   "delete <profile>",
+		Short: "Delete a profile and its stored token.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  profileDeleteRunE,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileSetCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+
+	profileSetCmd.Flags().StringVar(&profileTokenStore, "token-store", "", "Where to store the token for this profile. Accepted values: 'auto', 'keyring', 'file'.")
+	profileSetCmd.Flags().StringVar(&profileCredentialHelper, "credential-helper", "", "An external command storing the token for this profile, following the git-credential protocol. Set to an empty string to remove it.")
+	profileSetCmd.Flags().StringVar(&profileProjectID, "project-id", "", "The project ID the app commands use for this profile when --project-id is not set. Set to an empty string to remove it.")
+}
+
+func profileListRunE(cmd *cobra.Command, args []string) error {
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	current := profiles.Current
+	if current == "" {
+		current = profile.DefaultName
+	}
+
+	for _, name := range profiles.Names() {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+
+		endpoint := TempestProdAPI
+		if p, ok := profiles.Profiles[name]; ok && p.APIEndpoint != "" {
+			endpoint = p.APIEndpoint
+		}
+
+		cmd.Printf("%s %s\t%s\n", marker, name, endpoint)
+	}
+
+	return nil
+}
+
+func profileUseRunE(cmd *cobra.Command, args []string) error {
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	if _, err := profiles.Lookup(args[0]); err != nil {
+		return fmt.Errorf("%w\n Try: tempest auth login --profile %s", err, args[0])
+	}
+
+	profiles.Current = args[0]
+	if err := profile.Write(profiles); err != nil {
+		return fmt.Errorf("write profiles: %w", err)
+	}
+
+	cmd.Printf("Switched to profile %s.\n", args[0])
+
+	return nil
+}
+
+func profileSetRunE(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := profile.ValidateName(name); err != nil {
+		return err
+	}
+
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	p, ok := profiles.Profiles[name]
+	if !ok {
+		p = &profile.Profile{}
+		profiles.Profiles[name] = p
+	}
+
+	if cmd.Flags().Changed("api-endpoint") {
+		p.APIEndpoint = apiEndpoint
+	}
+
+	if cmd.Flags().Changed("token-store") {
+		if _, err := newTokenStore(name, profileTokenStore); err != nil {
+			return err
+		}
+		p.TokenStore = profileTokenStore
+	}
+
+	if cmd.Flags().Changed("credential-helper") {
+		p.CredentialHelper = profileCredentialHelper
+	}
+
+	if cmd.Flags().Changed("project-id") {
+		p.ProjectID = profileProjectID
+	}
+
+	if err := profile.Write(profiles); err != nil {
+		return fmt.Errorf("write profiles: %w", err)
+	}
+
+	cmd.Printf("Profile %s saved.\n", name)
+
+	return nil
+}
+
+func profileDeleteRunE(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	if _, err := profiles.Lookup(name); err != nil {
+		return err
+	}
+
+	store, err := newTokenStore(name, tokenStoreAuto)
+	if err != nil {
+		return err
+	}
+
+	if err := store.Delete(); err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+
+	delete(profiles.Profiles, name)
+	if profiles.Current == name {
+		profiles.Current = ""
+	}
+
+	if err := profile.Write(profiles); err != nil {
+		return fmt.Errorf("write profiles: %w", err)
+	}
+
+	cmd.Printf("Profile %s deleted.\n", name)
+
+	return nil
+}
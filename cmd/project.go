@@ -10,7 +10,6 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/messages"
-	"github.com/tempestdx/cli/internal/secret"
 	appapi "github.com/tempestdx/openapi/app"
 )
 
@@ -43,13 +42,16 @@ func init() {
 }
 
 func listProjects(cmd *cobra.Command, args []string) error {
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
@@ -134,13 +136,16 @@ func listProjects(cmd *cobra.Command, args []string) error {
 
 func getProject(cmd *cobra.Command, args []string) error {
 	projectID := args[0]
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
@@ -10,7 +10,6 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/messages"
-	"github.com/tempestdx/cli/internal/secret"
 	appapi "github.com/tempestdx/openapi/app"
 )
 
@@ -43,13 +42,16 @@ func init() {
 }
 
 func listRecipes(cmd *cobra.Command, args []string) error {
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
@@ -146,13 +148,16 @@ func listRecipes(cmd *cobra.Command, args []string) error {
 
 func getRecipe(cmd *cobra.Command, args []string) error {
 	recipeID := args[0]
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
@@ -12,7 +12,6 @@ import (
 	"github.com/charmbracelet/glamour"
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/messages"
-	"github.com/tempestdx/cli/internal/secret"
 	appapi "github.com/tempestdx/openapi/app"
 )
 
@@ -45,13 +44,16 @@ func init() {
 }
 
 func listResources(cmd *cobra.Command, args []string) error {
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
@@ -142,13 +144,16 @@ type KeyValue struct {
 
 func getResource(cmd *cobra.Command, args []string) error {
 	resourceID := args[0]
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: tempestTransport(token),
 		}),
 	)
 	if err != nil {
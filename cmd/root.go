@@ -1,20 +1,40 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
+	"github.com/tempestdx/cli/internal/oauth"
+	"github.com/tempestdx/cli/internal/profile"
 	"github.com/tempestdx/cli/internal/secret"
 	"github.com/tempestdx/cli/internal/version"
+	"golang.org/x/term"
+)
+
+const (
+	tokenStoreAuto    = "auto"
+	tokenStoreKeyring = "keyring"
+	tokenStoreFile    = "file"
 )
 
 var (
 	apiEndpoint string
 	cfgFile     string
-	tokenStore  secret.TokenStore
-	debugMode   bool
+	profileName string
+	// The default project ID of the active profile.
+	defaultProjectID string
+	tokenStore       secret.TokenStore
+	// Set when the token was obtained with `auth login --web`.
+	tokenRefresher secret.RefreshFunc
+	debugMode      bool
 
 	limitFlag int
 
@@ -22,6 +42,8 @@ var (
 		Use:     "tempest [command] [flags]",
 		Short:   "Tempest is a CLI tool to interact with the Tempest API and SDK",
 		Version: version.Version,
+
+		PersistentPreRunE: loadProfile,
 	}
 
 	// Add a command to generate the markdown documentation.
@@ -54,6 +76,7 @@ func init() {
 	rootCmd.AddCommand(docCmd)
 	rootCmd.PersistentFlags().StringVar(&apiEndpoint, "api-endpoint", TempestProdAPI, "The Tempest API endpoint to connect to.")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Full path to the config file (default is $WORKDIR/tempest.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "The profile to use (default is $TEMPEST_PROFILE or the current profile)")
 	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "Enable verbose logging")
 	// Customize the help and version flags
 	rootCmd.Flags().BoolP("help", "h", false, "Help for tempest")
@@ -63,31 +86,233 @@ func init() {
 	if envAPIEndpoint := os.Getenv("TEMPEST_API_ENDPOINT"); envAPIEndpoint != "" {
 		apiEndpoint = envAPIEndpoint
 	}
+}
+
+// loadProfile resolves the active profile and applies its settings.
+// Resolution order: --profile, TEMPEST_PROFILE, the current profile, "default".
+// An explicit --api-endpoint or TEMPEST_API_ENDPOINT takes precedence over the
+// endpoint stored in the profile.
+func loadProfile(cmd *cobra.Command, args []string) error {
+	profiles, err := profile.Read()
+	if err != nil {
+		return fmt.Errorf("read profiles: %w", err)
+	}
+
+	if profileName == "" {
+		profileName = os.Getenv("TEMPEST_PROFILE")
+	}
+	if profileName == "" {
+		profileName = profiles.Current
+	}
+	if profileName == "" {
+		profileName = profile.DefaultName
+	}
+
+	if err := profile.ValidateName(profileName); err != nil {
+		return err
+	}
+
+	storeKind := os.Getenv("TEMPEST_TOKEN_STORE")
+	helper := os.Getenv("TEMPEST_CREDENTIAL_HELPER")
+
+	// Commands such as `auth login` create profiles, so a missing profile is
+	// not an error here.
+	if p, err := profiles.Lookup(profileName); err == nil {
+		if p.APIEndpoint != "" && !cmd.Flags().Changed("api-endpoint") && os.Getenv("TEMPEST_API_ENDPOINT") == "" {
+			apiEndpoint = p.APIEndpoint
+		}
+
+		if storeKind == "" {
+			storeKind = p.TokenStore
+		}
+
+		if helper == "" {
+			helper = p.CredentialHelper
+		}
+
+		defaultProjectID = p.ProjectID
+	}
+
+	// A credential helper replaces the built-in token stores.
+	if helper != "" {
+		tokenStore = helperTokenStore(profileName, helper)
+		return nil
+	}
+
+	tokenStore, err = newTokenStore(profileName, storeKind)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func helperTokenStore(name, command string) *secret.Helper {
+	host := apiEndpoint
+	if u, err := url.Parse(apiEndpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	return &secret.Helper{
+		Command:  command,
+		Host:     host,
+		Username: name,
+	}
+}
+
+// newTokenStore returns the token store of the given kind for the named
+// profile. The "auto" kind uses the keyring and falls back to a file when no
+// keyring is available.
+func newTokenStore(name, kind string) (secret.TokenStore, error) {
+	switch kind {
+	case "", tokenStoreAuto:
+		f, err := fileTokenStore(name)
+		if err != nil {
+			return nil, err
+		}
+
+		return &secret.Fallback{
+			Primary:   keyringTokenStore(name),
+			Secondary: f,
+		}, nil
+	case tokenStoreKeyring:
+		return keyringTokenStore(name), nil
+	case tokenStoreFile:
+		return fileTokenStore(name)
+	default:
+		return nil, fmt.Errorf("invalid token store %q. Accepted values: '%s', '%s', '%s'", kind, tokenStoreAuto, tokenStoreKeyring, tokenStoreFile)
+	}
+}
+
+func keyringTokenStore(name string) *secret.Keyring {
+	// The default profile keeps using the original keyring entry, so tokens
+	// stored before profiles existed remain valid.
+	if name == profile.DefaultName {
+		return &secret.Keyring{}
+	}
+
+	return &secret.Keyring{Profile: name}
+}
+
+// fileTokenStore returns the file token store for the named profile, located
+// under the user configuration directory. Encrypted tokens are decrypted with
+// TEMPEST_TOKEN_PASSPHRASE, or a passphrase prompt when running in a terminal.
+func fileTokenStore(name string) (*secret.File, error) {
+	dir, err := profile.Dir()
+	if err != nil {
+		return nil, fmt.Errorf("locate config directory: %w", err)
+	}
+
+	return &secret.File{
+		Path:             filepath.Join(dir, "tokens", name),
+		Passphrase:       os.Getenv("TEMPEST_TOKEN_PASSPHRASE"),
+		PromptPassphrase: promptPassphrase,
+	}, nil
+}
+
+func promptPassphrase() (string, error) {
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", nil
+	}
+
+	fmt.Fprint(os.Stderr, "Token passphrase: ")
+	b, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Sources a Tempest token can be loaded from, as reported by `auth status`.
+const (
+	tokenSourceFile   = "file (TEMPEST_TOKEN_FILE)"
+	tokenSourceEnv    = "env (TEMPEST_TOKEN)"
+	tokenSourceStore  = "token store"
+	tokenSourceHelper = "credential helper"
+)
+
+// loadTempestToken loads the Tempest token from the environment or the token store.
+// Load order: TEMPEST_TOKEN_FILE, TEMPEST_TOKEN, token store (credential helper,
+// keyring or file).
+func loadTempestToken() (string, error) {
+	t, source, err := resolveTempestToken()
+	if err != nil {
+		if source == tokenSourceFile {
+			return "", fmt.Errorf("read token file: %w", err)
+		}
+		return "", fmt.Errorf("could not get the auth token from the token store: %w.\nPlease set either TEMPEST_TOKEN_FILE, TEMPEST_TOKEN or run `%s` first", err, loginCommand())
+	}
 
-	tokenStore = &secret.Keyring{}
+	return t, nil
 }
 
-// loadTempestToken loads the Tempest token from the environment or the keyring.
-// Load order: TEMPEST_TOKEN_FILE, TEMPEST_TOKEN, keyring.
-func loadTempestToken(cmd *cobra.Command) string {
+// resolveTempestToken returns the Tempest token along with the source it was
+// loaded from. The source is returned even when loading fails.
+func resolveTempestToken() (string, string, error) {
 	if t := os.Getenv("TEMPEST_TOKEN_FILE"); t != "" {
 		b, err := os.ReadFile(t)
 		if err != nil {
-			cmd.PrintErrf("read token file: %v\n", err)
-			os.Exit(1)
+			return "", tokenSourceFile, err
 		}
-		return string(b)
+		return strings.TrimSpace(string(b)), tokenSourceFile, nil
 	}
 
 	if t := os.Getenv("TEMPEST_TOKEN"); t != "" {
-		return t
+		return t, tokenSourceEnv, nil
+	}
+
+	source := tokenSourceStore
+	if _, ok := tokenStore.(*secret.Helper); ok {
+		source = tokenSourceHelper
 	}
 
 	t, err := tokenStore.Get()
 	if err != nil {
-		cmd.PrintErrf("Could not get the auth token from the system keyring: %v.\nPlease set either TEMPEST_TOKEN_FILE, TEMPEST_TOKEN or run `tempest auth login` first.\n", err)
-		os.Exit(1)
+		return "", source, err
+	}
+
+	// Tokens from `auth login --web` expire, and are refreshed when needed.
+	if creds, ok := oauth.DecodeToken(t); ok {
+		if creds.Expired() {
+			creds, err = refreshStoredToken(context.TODO(), creds)
+			if err != nil {
+				return "", source, err
+			}
+		}
+
+		tokenRefresher = func(ctx context.Context) (string, error) {
+			refreshed, err := refreshStoredToken(ctx, creds)
+			if err != nil {
+				return "", err
+			}
+			creds = refreshed
+			return creds.AccessToken, nil
+		}
+
+		return creds.AccessToken, source, nil
+	}
+
+	return t, source, nil
+}
+
+// tempestTransport returns the transport authenticating requests to the
+// Tempest API with token. Tokens that can be refreshed are refreshed when the
+// API rejects them.
+func tempestTransport(token string) *secret.Transport {
+	if tokenRefresher != nil {
+		return secret.NewRefreshingTransport(token, tokenRefresher)
+	}
+
+	return secret.NewTransportWithToken(token)
+}
+
+// loginCommand returns the login command for the active profile.
+func loginCommand() string {
+	if profileName == "" || profileName == profile.DefaultName {
+		return "tempest auth login"
 	}
 
-	return t
+	return "tempest auth login --profile " + profileName
 }
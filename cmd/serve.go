@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/tempestdx/cli/internal/config"
 	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/schema"
 	"github.com/tempestdx/cli/internal/secret"
 	appapi "github.com/tempestdx/openapi/app"
 	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
@@ -24,19 +27,33 @@ import (
 const (
 	TempestProdAPI  = "https://developer.tempestdx.com/api/v1"
 	pollingInterval = 5 * time.Second
+
+	validateOutputOff     = "off"
+	validateOutputWarn    = "warn"
+	validateOutputEnforce = "enforce"
 )
 
 var (
 	appServeHealthcheckInterval time.Duration
 	appExecutionTimeout         time.Duration
+	appServeTokenReload         time.Duration
+	appServeMetricsAddr         string
+	appServeValidateOutput      string
 	logger                      *slog.Logger
 
+	// Token reloads by reason, and failed reloads, exposed on --metrics-addr.
+	tokenReloads      = expvar.NewMap("tempest_token_reloads")
+	tokenReloadErrors = expvar.NewInt("tempest_token_reload_errors")
+
 	serveCmd = &cobra.Command{
 		Use:   "serve [<app-id>:<app-version>]",
 		Short: "Facilitates the serving of Tempest apps.",
 		Long: `The serve command is used to start your Tempest apps and orchestrate commands from the Tempest API.
 
-If no app ID and version is provided, it will serve all apps from the tempest.yaml configuration file.`,
+If no app ID and version is provided, it will serve all apps from the tempest.yaml configuration file.
+
+The token is reloaded when the file behind TEMPEST_TOKEN_FILE changes, at the --token-reload-interval,
+and when the Tempest API rejects it, so rotated tokens are picked up without a restart.`,
 		Args: cobra.RangeArgs(0, 1),
 		RunE: serveRunE,
 	}
@@ -47,6 +64,9 @@ func init() {
 
 	serveCmd.Flags().DurationVarP(&appServeHealthcheckInterval, "healthcheck-interval", "i", 5*time.Minute, "The interval at which to perform healthchecks.")
 	serveCmd.Flags().DurationVarP(&appExecutionTimeout, "app-execution-timeout", "t", 5*time.Minute, "The timeout for the app execution operation.")
+	serveCmd.Flags().DurationVar(&appServeTokenReload, "token-reload-interval", 5*time.Minute, "The interval at which to reload the token. Set to 0 to disable.")
+	serveCmd.Flags().StringVar(&appServeMetricsAddr, "metrics-addr", "", "The address on which to expose metrics in expvar format, e.g. 'localhost:9090'. Disabled by default.")
+	serveCmd.Flags().StringVar(&appServeValidateOutput, "validate-output", validateOutputOff, "Validate the properties returned by the apps against their properties schema. Accepted values: 'off', 'warn' (log violations), 'enforce' (report violations to Tempest as task errors).")
 }
 
 func serveRunE(cmd *cobra.Command, args []string) error {
@@ -59,6 +79,12 @@ func serveRunE(cmd *cobra.Command, args []string) error {
 		Level: logLevel,
 	}))
 
+	switch appServeValidateOutput {
+	case validateOutputOff, validateOutputWarn, validateOutputEnforce:
+	default:
+		return fmt.Errorf("invalid --validate-output %q. Accepted values: '%s', '%s', '%s'", appServeValidateOutput, validateOutputOff, validateOutputWarn, validateOutputEnforce)
+	}
+
 	var id, version string
 	if len(args) > 0 {
 		var err error
@@ -68,18 +94,37 @@ func serveRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	token := loadTempestToken(cmd)
+	token, err := loadTempestToken()
+	if err != nil {
+		return err
+	}
 
 	cfg, cfgDir, err := config.ReadConfig()
 	if err != nil {
 		return err
 	}
 
+	reloader := &secret.Reloader{
+		Load:     reloadTempestToken,
+		File:     os.Getenv("TEMPEST_TOKEN_FILE"),
+		Interval: appServeTokenReload,
+		OnReload: logTokenReload,
+	}
+	reloader.Transport = secret.NewRefreshingTransport(token, reloader.Refresh)
+
+	reloadCtx, stopReload := context.WithCancel(context.Background())
+	defer stopReload()
+	go reloader.Run(reloadCtx)
+
+	if appServeMetricsAddr != "" {
+		go serveMetrics(appServeMetricsAddr)
+	}
+
 	tempestClient, err := appapi.NewClientWithResponses(
 		apiEndpoint,
 		appapi.WithHTTPClient(&http.Client{
 			Timeout:   10 * time.Second,
-			Transport: secret.NewTransportWithToken(token),
+			Transport: reloader.Transport,
 		}),
 	)
 	if err != nil {
@@ -136,11 +181,66 @@ func serveRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// reloadTempestToken loads the token again from its source. Tokens from
+// `auth login --web` are refreshed when the API rejects them.
+func reloadTempestToken(ctx context.Context, reason string) (string, error) {
+	// A credential helper would return its cached token.
+	if h, ok := tokenStore.(*secret.Helper); ok {
+		h.Refresh()
+	}
+
+	if reason == secret.ReloadReasonUnauthorized && tokenRefresher != nil {
+		return tokenRefresher(ctx)
+	}
+
+	return loadTempestToken()
+}
+
+func logTokenReload(reason string, changed bool, err error) {
+	if err != nil {
+		tokenReloadErrors.Add(1)
+		logger.Error("reload token", "reason", reason, "error", err)
+		return
+	}
+
+	if !changed {
+		logger.Debug("token unchanged", "reason", reason)
+		return
+	}
+
+	tokenReloads.Add(reason, 1)
+	logger.Info("token reloaded", "reason", reason)
+}
+
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", expvar.Handler())
+
+	logger.Info("serving metrics", "addr", addr)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		logger.Error("serve metrics", "error", err)
+	}
+}
+
 func startPolling(runner runner.Runner, tempestClient *appapi.ClientWithResponses) {
 	logger := logger.With("app_id", runner.AppID, "version", runner.Version)
 
 	logger.Info("start polling")
 
+	var propertiesSchemas map[string]*schema.Schema
+	if appServeValidateOutput != validateOutputOff {
+		var err error
+		propertiesSchemas, err = loadPropertiesSchemas(runner.Client)
+		if err != nil {
+			logger.Error("load properties schemas, output will not be validated", "error", err)
+		}
+	}
+
 	for {
 		logger.Debug("polling for next task")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -252,6 +352,14 @@ func startPolling(runner runner.Runner, tempestClient *appapi.ClientWithResponse
 
 				logger.Debug("app operation executed", "output", res)
 
+				// Deleted resources are not validated, as the SDK does.
+				if op != appv1.ResourceOperation_RESOURCE_OPERATION_DELETE {
+					if err := validateOutput(logger, tempestClient, nextTask.JSON200.TaskId, propertiesSchemas[v.Resource.Type], res.Msg.Resource); err != nil {
+						time.Sleep(pollingInterval)
+						continue
+					}
+				}
+
 				// prepare the response depending on the operation
 				var response appapi.ReportResponse_Response
 
@@ -333,6 +441,11 @@ func startPolling(runner runner.Runner, tempestClient *appapi.ClientWithResponse
 					continue
 				}
 
+				if err := validateOutput(logger, tempestClient, nextTask.JSON200.TaskId, propertiesSchemas[v.Resource.Type], res.Msg.Resources...); err != nil {
+					time.Sleep(pollingInterval)
+					continue
+				}
+
 				resources := make([]appapi.Resource, len(res.Msg.Resources))
 				for i, r := range res.Msg.Resources {
 					properties := r.Properties.AsMap()
@@ -399,6 +512,56 @@ func startPolling(runner runner.Runner, tempestClient *appapi.ClientWithResponse
 	}
 }
 
+// loadPropertiesSchemas returns the properties schema of each resource type of
+// the app.
+func loadPropertiesSchemas(client appv1connect.AppServiceClient) (map[string]*schema.Schema, error) {
+	des, err := client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("describe app: %w", err)
+	}
+
+	schemas := make(map[string]*schema.Schema)
+	for _, rd := range des.Msg.ResourceDefinitions {
+		sch, err := propertiesSchemaFor(rd)
+		if err != nil {
+			return nil, err
+		}
+		if sch != nil {
+			schemas[rd.Type] = sch
+		}
+	}
+
+	return schemas, nil
+}
+
+// validateOutput validates the properties of the resources returned for a
+// task. Violations are logged in warn mode. In enforce mode, the task is
+// reported to Tempest as failed, and an error is returned.
+func validateOutput(logger *slog.Logger, tempestClient *appapi.ClientWithResponses, taskID string, propertiesSchema *schema.Schema, resources ...*appv1.Resource) error {
+	violations, err := propertiesViolations(propertiesSchema, resources...)
+	if err != nil {
+		logger.Error("validate output", "error", err)
+		return nil
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if appServeValidateOutput != validateOutputEnforce {
+		logger.Warn("properties do not match the properties schema", "task_id", taskID, "violations", violations)
+		return nil
+	}
+
+	err = fmt.Errorf("properties do not match the properties schema: %s", strings.Join(violations, "; "))
+	if tempestErr := postTempestError(tempestClient, taskID, err); tempestErr != nil {
+		logger.Error("report task", "task_id", taskID, "error", tempestErr)
+	}
+	logger.Error("validate output", "task_id", taskID, "error", err)
+
+	return err
+}
+
 func startHealthCheck(
 	runner runner.Runner,
 	tempestClient *appapi.ClientWithResponses,
@@ -0,0 +1,663 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/profile"
+	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/shell"
+	"github.com/tempestdx/cli/internal/state"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tidwall/pretty"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// shellHelp is printed by the help command.
+const shellHelp = `Commands:
+  describe                            Print the resource types of the app.
+  create TYPE [INPUT]                 Create a resource.
+  read TYPE ID                        Read a resource.
+  update TYPE ID [INPUT]              Update a resource.
+  delete TYPE ID                      Delete a resource.
+  list TYPE                           List the resources of a type, up to 100 pages.
+  action TYPE ID ACTION [INPUT]       Run an action on a resource.
+  health TYPE                         Run the health check of a type.
+  reload                              Rebuild and restart the app.
+  vars                                Print the variables.
+  help                                Print the commands.
+  exit                                Leave the shell.`
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <app-id>:<app-version>",
+	Short: "Run the operations of an app interactively.",
+	Long: `The shell command starts the app once, and reads commands running its
+operations, without paying for the build and startup of the app on each one.
+
+` + shellHelp + `
+
+Inputs are JSON objects. The result of each command is stored in the ${_}
+variable, and in a named one with an assignment: db = create database {...}.
+Variables are referred to in arguments as ${db} or ${db.properties.name}.
+IDs may be the aliases of the resources recorded by 'tempest app test --as'.
+
+Tab completes commands, resource types, actions and variables. The history is
+kept across sessions. Lines read from a pipe are run as a script.`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          shellRunE,
+	SilenceErrors: true,
+}
+
+func init() {
+	appCmd.AddCommand(shellCmd)
+
+	addRequestFlags(shellCmd.Flags())
+}
+
+// shellMaxListPages is the number of pages list fetches at most.
+const shellMaxListPages = 100
+
+// shellCommands are the commands of the shell, completed in this order.
+var shellCommands = []string{"describe", "create", "read", "update", "delete", "list", "action", "health", "reload", "vars", "help", "exit", "quit"}
+
+// appShell is a shell session, running the commands against an app.
+type appShell struct {
+	out         io.Writer
+	logs        io.Writer
+	color       bool
+	cfg         *config.TempestConfig
+	cfgDir      string
+	appID       string
+	appVersion  *config.AppVersion
+	runner      runner.Runner
+	stop        func()
+	definitions map[string]*appv1.ResourceDefinition
+	metadata    *appv1.Metadata
+	ev          []*appv1.EnvironmentVariable
+	state       *state.State
+	vars        shell.Vars
+	// runnerOpts are added to the options of the runner of the app.
+	runnerOpts []runner.Option
+}
+
+func shellRunE(cmd *cobra.Command, args []string) error {
+	return runShell(cmd, args)
+}
+
+// runShell runs the shell of the app, whose runner gets the options on top of
+// the ones of the shell.
+func runShell(cmd *cobra.Command, args []string, runnerOpts ...runner.Option) error {
+	id, version, err := splitAppVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	appVersion := cfg.LookupAppByVersion(id, version)
+	if appVersion == nil {
+		return fmt.Errorf("app version %s:%s not found in config", id, version)
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	st, err := state.Load(state.Path(cfgDir, id, version))
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+
+	s := &appShell{
+		out:        cmd.OutOrStdout(),
+		logs:       cmd.ErrOrStderr(),
+		cfg:        cfg,
+		cfgDir:     cfgDir,
+		appID:      id,
+		appVersion: appVersion,
+		metadata:   metadata,
+		ev:         ev,
+		state:      st,
+		vars:       shell.Vars{},
+		runnerOpts: runnerOpts,
+	}
+
+	// Lines read from a pipe are a script, without prompt nor completion.
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		if err := s.start(); err != nil {
+			return err
+		}
+		defer func() { s.stop() }()
+
+		return s.runScript(cmd, os.Stdin)
+	}
+
+	oldState, err := term.MakeRaw(int(syscall.Stdin))
+	if err != nil {
+		return fmt.Errorf("make terminal raw: %w", err)
+	}
+	defer func() {
+		_ = term.Restore(int(syscall.Stdin), oldState)
+	}()
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, fmt.Sprintf("%s> ", args[0]))
+	if w, h, err := term.GetSize(int(syscall.Stdout)); err == nil && w > 0 {
+		_ = t.SetSize(w, h)
+	}
+	s.out, s.logs = t, t
+	s.color = true
+
+	if dir, err := profile.Dir(); err == nil {
+		if h, err := shell.LoadHistory(filepath.Join(dir, "shell_history")); err == nil {
+			t.History = h
+		}
+	}
+
+	completer := s.completer()
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+
+		newLine, newPos, matches := completer.Complete(line, pos)
+		if len(matches) > 0 {
+			fmt.Fprintln(t, strings.Join(matches, "  "))
+		}
+		return newLine, newPos, true
+	}
+
+	fmt.Fprintf(t, "Starting %s...\n", args[0])
+	if err := s.start(); err != nil {
+		return err
+	}
+	defer func() { s.stop() }()
+	fmt.Fprintln(t, "Type help for the commands, and exit or Ctrl-D to leave.")
+
+	for {
+		line, err := t.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, term.ErrPasteIndicator) {
+			return err
+		}
+
+		exit, err := s.run(line)
+		if err != nil {
+			fmt.Fprintf(t, "❌ %s\n", err)
+		}
+		if exit {
+			return nil
+		}
+	}
+}
+
+// runScript runs the lines of r, and stops at the first failing one.
+func (s *appShell) runScript(cmd *cobra.Command, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		exit, err := s.run(line)
+		if err != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		if exit {
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
+
+// start builds and starts the app, and describes it.
+func (s *appShell) start() error {
+	if !appPreserveBuildDir {
+		err := generateBuildDir(s.cfg, s.cfgDir, s.appID, s.appVersion.Version)
+		if err != nil {
+			return fmt.Errorf("generate build dir: %w", err)
+		}
+	}
+
+	opts := append([]runner.Option{runner.WithOutput(s.logs, s.logs)}, s.runnerOpts...)
+	r, cancel, err := runner.StartApp(context.Background(), s.cfg, s.cfgDir, s.appID, s.appVersion, opts...)
+	if err != nil {
+		return fmt.Errorf("start app: %w", err)
+	}
+	s.runner, s.stop = r, cancel
+
+	des, err := r.Client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		cancel()
+		s.stop = func() {}
+		return fmt.Errorf("reach private app: %w", err)
+	}
+
+	s.definitions = make(map[string]*appv1.ResourceDefinition)
+	for _, rd := range des.Msg.ResourceDefinitions {
+		s.definitions[rd.Type] = rd
+	}
+
+	return nil
+}
+
+func (s *appShell) completer() *shell.Completer {
+	return &shell.Completer{
+		Commands: shellCommands,
+		Args: func(command string, args []string) []string {
+			switch {
+			case len(args) == 0 && slices.Contains([]string{"create", "read", "update", "delete", "list", "action", "health"}, command):
+				return slices.Sorted(maps.Keys(s.definitions))
+			case len(args) == 1 && slices.Contains([]string{"read", "update", "delete", "action"}, command):
+				var aliases []string
+				for _, r := range s.state.Resources {
+					if r.Type == args[0] && r.Alias != "" {
+						aliases = append(aliases, r.Alias)
+					}
+				}
+				return aliases
+			case len(args) == 2 && command == "action":
+				var names []string
+				if rd, ok := s.definitions[args[0]]; ok {
+					for _, a := range rd.Actions {
+						names = append(names, a.Name)
+					}
+				}
+				return names
+			}
+			return nil
+		},
+		Vars: func() []string {
+			return slices.Sorted(maps.Keys(s.vars))
+		},
+	}
+}
+
+// run runs a line, and reports whether the shell must exit.
+func (s *appShell) run(text string) (bool, error) {
+	line, err := shell.Parse(text)
+	if err != nil {
+		return false, err
+	}
+
+	for i, a := range line.Args {
+		if line.Args[i], err = s.vars.Expand(a); err != nil {
+			return false, err
+		}
+	}
+
+	var result any
+	switch line.Name {
+	case "":
+		return false, nil
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		fmt.Fprintln(s.out, shellHelp)
+		return false, nil
+	case "vars":
+		for _, name := range slices.Sorted(maps.Keys(s.vars)) {
+			b, _ := json.Marshal(s.vars[name])
+			fmt.Fprintf(s.out, "%s = %s\n", name, b)
+		}
+		return false, nil
+	case "reload":
+		s.stop()
+		s.stop = func() {}
+		fmt.Fprintln(s.out, "Reloading the app...")
+		if err := s.start(); err != nil {
+			// The app must run for the next commands, there is no point
+			// in going on.
+			return true, err
+		}
+		fmt.Fprintln(s.out, "✅ App reloaded.")
+		return false, nil
+	case "describe":
+		result, err = s.describe(line.Args)
+	case "create", "read", "update", "delete":
+		result, err = s.operation(line.Name, line.Args)
+	case "list":
+		result, err = s.list(line.Args)
+	case "action":
+		result, err = s.action(line.Args)
+	case "health":
+		result, err = s.health(line.Args)
+	default:
+		return false, fmt.Errorf("unknown command %s. Type help for the commands", line.Name)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.vars[shell.LastVar] = result
+	if line.Var != "" {
+		s.vars[line.Var] = result
+	}
+
+	// The definitions are summarized, the full document is in the variables.
+	if line.Name == "describe" {
+		return false, nil
+	}
+
+	return false, s.print(result)
+}
+
+func (s *appShell) describe(args []string) (any, error) {
+	if len(args) > 0 {
+		return nil, errors.New("usage: describe")
+	}
+
+	types := slices.Sorted(maps.Keys(s.definitions))
+	for _, t := range types {
+		rd := s.definitions[t]
+		var ops []string
+		for op, ok := range map[string]bool{
+			"create": rd.CreateSupported,
+			"read":   rd.ReadSupported,
+			"update": rd.UpdateSupported,
+			"delete": rd.DeleteSupported,
+			"list":   rd.ListSupported,
+			"health": rd.HealthcheckSupported,
+		} {
+			if ok {
+				ops = append(ops, op)
+			}
+		}
+		slices.Sort(ops)
+
+		fmt.Fprintf(s.out, "%s: %s\n", t, strings.Join(ops, ", "))
+		for _, a := range rd.Actions {
+			fmt.Fprintf(s.out, "  action %s: %s\n", a.Name, a.Description)
+		}
+	}
+
+	defs := make([]any, 0, len(types))
+	for _, t := range types {
+		doc, err := protoDocument(s.definitions[t])
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, doc)
+	}
+
+	return map[string]any{"resource_definitions": defs}, nil
+}
+
+var shellUsages = map[string]string{
+	"create": "create TYPE [INPUT]",
+	"read":   "read TYPE ID",
+	"update": "update TYPE ID [INPUT]",
+	"delete": "delete TYPE ID",
+}
+
+func (s *appShell) operation(name string, args []string) (any, error) {
+	minArgs, maxArgs := 2, 2
+	switch name {
+	case "create":
+		minArgs = 1
+	case "update":
+		maxArgs = 3
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		return nil, fmt.Errorf("usage: %s", shellUsages[name])
+	}
+
+	rd, err := s.definition(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := &appv1.ExecuteResourceOperationRequest{
+		Resource:             &appv1.Resource{Type: rd.Type},
+		Metadata:             s.metadata,
+		EnvironmentVariables: s.ev,
+	}
+
+	var input string
+	switch name {
+	case "create":
+		req.Operation = appv1.ResourceOperation_RESOURCE_OPERATION_CREATE
+		if len(args) > 1 {
+			input = args[1]
+		}
+	case "read":
+		req.Operation = appv1.ResourceOperation_RESOURCE_OPERATION_READ
+	case "update":
+		req.Operation = appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE
+		if len(args) > 2 {
+			input = args[2]
+		}
+	case "delete":
+		req.Operation = appv1.ResourceOperation_RESOURCE_OPERATION_DELETE
+	}
+
+	if name != "create" {
+		if req.Resource.ExternalId, err = s.externalID(rd.Type, args[1]); err != nil {
+			return nil, err
+		}
+	}
+	if name == "create" || name == "update" {
+		if req.Input, err = shellInput(input); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := s.runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "create":
+		err = s.state.Add(rd.Type, res.Msg.Resource.GetExternalId(), "")
+	case "delete":
+		s.state.Remove(rd.Type, req.Resource.ExternalId)
+	}
+	if err == nil && (name == "create" || name == "delete") {
+		err = s.state.Save()
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "⚠️  Failed to update the state %s: %s\n", s.state.Path(), err)
+	}
+
+	return protoDocument(res.Msg)
+}
+
+func (s *appShell) list(args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: list TYPE")
+	}
+
+	rd, err := s.definition(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// The next token is kept in the result when there are more pages.
+	var resources []*appv1.Resource
+	var next string
+	for range shellMaxListPages {
+		res, err := s.runner.Client.ListResources(context.TODO(), connect.NewRequest(&appv1.ListResourcesRequest{
+			Resource: &appv1.Resource{Type: rd.Type},
+			Metadata: s.metadata,
+			Next:     next,
+		}))
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, res.Msg.GetResources()...)
+		if res.Msg.Next != "" && res.Msg.Next == next {
+			return nil, fmt.Errorf("the app returned the next page token %q of the page it was given", next)
+		}
+		if next = res.Msg.Next; next == "" {
+			break
+		}
+	}
+
+	return protoDocument(&appv1.ListResourcesResponse{Resources: resources, Next: next})
+}
+
+func (s *appShell) action(args []string) (any, error) {
+	if len(args) < 3 || len(args) > 4 {
+		return nil, errors.New("usage: action TYPE ID ACTION [INPUT]")
+	}
+
+	rd, err := s.definition(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(rd.Actions, func(a *appv1.ActionDefinition) bool { return a.Name == args[2] }) {
+		return nil, fmt.Errorf("action %s not found for type %s", args[2], rd.Type)
+	}
+
+	externalID, err := s.externalID(rd.Type, args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var input string
+	if len(args) > 3 {
+		input = args[3]
+	}
+	in, err := shellInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.runner.Client.ExecuteResourceAction(context.TODO(), connect.NewRequest(&appv1.ExecuteResourceActionRequest{
+		Resource:             &appv1.Resource{Type: rd.Type, ExternalId: externalID},
+		Action:               args[2],
+		Input:                in,
+		Metadata:             s.metadata,
+		EnvironmentVariables: s.ev,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return protoDocument(res.Msg)
+}
+
+func (s *appShell) health(args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: health TYPE")
+	}
+
+	rd, err := s.definition(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.runner.Client.HealthCheck(context.TODO(), connect.NewRequest(&appv1.HealthCheckRequest{
+		Type: rd.Type,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return protoDocument(res.Msg)
+}
+
+func (s *appShell) definition(resourceType string) (*appv1.ResourceDefinition, error) {
+	rd, ok := s.definitions[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in app. Available types: %s", resourceType, strings.Join(slices.Sorted(maps.Keys(s.definitions)), ", "))
+	}
+
+	return rd, nil
+}
+
+// externalID returns the external ID of the resource with the alias id in the
+// state, or id itself.
+func (s *appShell) externalID(resourceType, id string) (string, error) {
+	r := s.state.Lookup(id)
+	if r == nil {
+		return id, nil
+	}
+	if r.Type != resourceType {
+		return "", fmt.Errorf("alias %s is a resource of type %s, not %s", id, r.Type, resourceType)
+	}
+
+	return r.ExternalID, nil
+}
+
+// print writes a result as indented JSON, colored on a terminal.
+func (s *appShell) print(result any) error {
+	b, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal output: %w", err)
+	}
+	if s.color {
+		b = pretty.Color(b, nil)
+	}
+
+	_, err = fmt.Fprintf(s.out, "%s\n", b)
+	return err
+}
+
+// shellInput parses the JSON object input of a command, empty when omitted.
+func shellInput(input string) (*structpb.Struct, error) {
+	m := map[string]any{}
+	if input != "" {
+		if err := json.Unmarshal([]byte(input), &m); err != nil {
+			return nil, fmt.Errorf("invalid input, expected a JSON object: %w", err)
+		}
+	}
+
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	return s, nil
+}
+
+// protoDocument returns the JSON document of a message, with the field names
+// of the app protocol.
+func protoDocument(msg proto.Message) (any, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal response: %w", err)
+	}
+
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	return doc, nil
+}
@@ -3,19 +3,40 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"math/rand/v2"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tempestdx/cli/internal/cassette"
 	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/cover"
+	"github.com/tempestdx/cli/internal/dotenv"
+	"github.com/tempestdx/cli/internal/pemcheck"
 	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/schema"
+	"github.com/tempestdx/cli/internal/snapshot"
+	"github.com/tempestdx/cli/internal/state"
+	"github.com/tempestdx/cli/internal/suite"
 	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
 	"github.com/tidwall/pretty"
+	"golang.org/x/term"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -27,33 +48,151 @@ var (
 	testDatasourceInput      string
 	testProjectID            string
 	testEnvironmentVariables []string
+	testSuite                string
+	testSet                  []string
+	testEnvFile              string
+	testAction               string
+	testMetadataFile         string
+	testMaxPages             int
+	testNext                 string
+	testEnvSecrets           []string
+	testEnvCertificates      []string
+	testEnvPrivateKeys       []string
+	testEnvPublicKeys        []string
+	testOutput               string
+	testJUnit                string
+	testSummary              string
+	testSnapshot             bool
+	testUpdateSnapshots      bool
+	testSnapshotName         string
+	testSnapshotIgnore       []string
+	testRecord               string
+	testReplay               string
+	testRedactQuery          []string
+	testRedactFields         []string
+	testAs                   string
+	testCover                bool
+	testCoverProfile         string
+	testCoverDir             string
+
+	// testSnapshots stores the snapshots of responses, when --snapshot is set.
+	testSnapshots *snapshot.Store
+	// The resources created by the tests of the app version.
+	testState *state.State
 
 	testCmd = &cobra.Command{
-		Use:           "test <app-id>:<app-version>",
-		Short:         "Test an app locally.",
-		Long:          `The test command is used to test the functionality of a Tempest App.`,
+		Use:   "test <app-id>:<app-version>",
+		Short: "Test an app locally.",
+		Long: `The test command is used to test the functionality of a Tempest App.
+
+Use --suite to run the ordered scenarios of a YAML test suite against a single
+running app, instead of a single operation.
+
+Use --output json or --output yaml to print the result for scripts and CI
+pipelines, and --junit to write a JUnit XML report of a suite run.
+
+Use --snapshot to compare responses with the snapshots stored in the
+testdata/snapshots directory of the app, and --update-snapshots to accept the
+changes. Snapshots are stored on the first run.
+
+The resources created by the tests are recorded in a state file of the app
+version, under .tempest/state. Use --as to give a created resource an alias,
+which --external-id accepts in later tests. Run 'tempest app test cleanup' to
+delete the recorded resources.
+
+Use --cover to measure the statements of the apps run by the tests. The app is
+built with coverage instrumentation, and stopped gracefully at the end so that
+its counters are written.`,
 		Args:          cobra.ExactArgs(1),
 		RunE:          testRunE,
 		SilenceErrors: true,
 	}
 )
 
+// Document formats accepted by --output.
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// testMaxListPages is the number of pages the 'list' operation fetches at
+// most when --max-pages is 0. The token of the next page is printed past it.
+const testMaxListPages = 1000
+
 func init() {
 	appCmd.AddCommand(testCmd)
 
-	testCmd.Flags().StringVarP(&testOperation, "operation", "o", "", "(REQUIRED) The operation to test. Accepted values: 'create', 'update', 'delete', 'list', 'read'.")
+	testCmd.Flags().StringVarP(&testOperation, "operation", "o", "", "(REQUIRED) The operation to test. Accepted values: 'create', 'update', 'delete', 'list', 'read', 'healthcheck'.")
 	testCmd.Flags().StringVarP(&testType, "type", "t", "", "(REQUIRED) The type of the resource to test.")
+	testCmd.Flags().StringVar(&testAction, "action", "", "The name of the action to test, instead of an operation. The --external-id identifies the resource to run the action on.")
 
-	testCmd.Flags().StringVarP(&testInput, "input", "i", "", "The input to the operation. JSON formatted input options to the operation.")
+	testCmd.Flags().StringVarP(&testInput, "input", "i", "", "The input to the operation. JSON formatted input options to the operation, @file.json, @file.yaml, or - to read from stdin. When omitted on a terminal, the input is prompted for.")
+	testCmd.Flags().StringArrayVar(&testSet, "set", nil, "Set a field of the input, overriding --input. Format: path.to.field=value. Values are parsed as JSON when valid, and as strings otherwise.")
 	testCmd.Flags().StringArrayVar(&testEnvironmentVariables, "env", nil, "Environment variables to set for the operation. Format: KEY=VALUE.")
-	testCmd.Flags().StringVarP(&testParentExternalId, "parent-external-id", "p", "", "The external ID of the parent resource. Only required when testing sub-resources.")
-	testCmd.Flags().StringVarP(&testExternalID, "external-id", "e", "", "The external ID of the resource to test. Only required when testing 'update', 'delete', or 'read' operations.")
+	addSuiteFlags(testCmd.Flags())
+	testCmd.Flags().StringVar(&testEnvFile, "env-file", "", "A dotenv file of environment variables to set for the operation. Prefix keys with a type to set typed variables, e.g. secret:API_KEY=value. Accepted types: 'var', 'secret', 'certificate', 'private_key', 'public_key'. Overridden by --env.")
+	testCmd.Flags().StringVarP(&testParentExternalId, "parent-external-id", "p", "", "The external ID of the parent resource. Not supported yet: the app protocol has no field to send it to the app.")
+	testCmd.Flags().StringVarP(&testExternalID, "external-id", "e", "", "The external ID of the resource to test, or the alias given to it with --as. Only required when testing 'update', 'delete', or 'read' operations, and actions. With an alias, --type defaults to the type of the resource.")
+	testCmd.Flags().StringVar(&testAs, "as", "", "An alias for the resource created by the 'create' operation, which --external-id accepts in later tests.")
+
+	testCmd.Flags().StringVar(&testProjectID, "project-id", "", "The project ID to use for the operation. If not specified, the project ID of the profile is used, or a random one is generated.")
+	testCmd.Flags().StringVar(&testMetadataFile, "metadata", "", "A YAML file with the metadata of the operation: project_id, project_name, author and owners. Authors and owners have a name, an email and a type ('user' or 'team'). --project-id takes precedence.")
+	testCmd.Flags().StringVar(&testDatasourceInput, "datasource-input", "", "The datasource input for the 'list' operation. Not supported yet: the app protocol has no field to send it to the app.")
+	testCmd.Flags().IntVar(&testMaxPages, "max-pages", 0, "The maximum number of pages to fetch for the 'list' operation. 0 fetches all pages, up to 1000. The token of the next page is printed when more pages are available.")
+	testCmd.Flags().StringVar(&testNext, "next", "", "The page token to start the 'list' operation from, as printed by a previous run.")
+	testCmd.Flags().StringVar(&testSuite, "suite", "", "Path to a YAML test suite to run. When set, --operation and --type are not used.")
+	testCmd.Flags().StringVar(&testSnapshotName, "snapshot-name", "", "The name of the snapshot of the operation. Defaults to TYPE/OPERATION, or TYPE/action-ACTION. Use distinct names to snapshot an operation with several inputs.")
+	testCmd.Flags().BoolVar(&testCover, "cover", false, "Build the app with coverage instrumentation of the packages under apps/, and print the statement coverage of the test.")
+	testCmd.Flags().StringVar(&testCoverProfile, "coverprofile", "", "Write the coverage profile of the test to this file, for go tool cover. Implies --cover.")
+	testCmd.Flags().StringVar(&testCoverDir, "coverdir", "", "Keep the coverage counters of the app in this directory instead of a temporary one. The coverage of several test runs sharing the directory is merged. Implies --cover.")
+}
+
+// addSuiteFlags adds the flags of the environment, the reports, the snapshots
+// and the cassettes of the tests, which app debug shares to run suites.
+func addSuiteFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&testEnvSecrets, "env-secret", nil, "Secret environment variables to set for the operation. Format: KEY=VALUE or KEY=@file.")
+	fs.StringArrayVar(&testEnvCertificates, "env-cert", nil, "PEM encoded certificate environment variables to set for the operation. Format: KEY=@file.pem or KEY=VALUE.")
+	fs.StringArrayVar(&testEnvPrivateKeys, "env-private-key", nil, "PEM encoded private key environment variables to set for the operation. Format: KEY=@file.pem or KEY=VALUE.")
+	fs.StringArrayVar(&testEnvPublicKeys, "env-public-key", nil, "PEM encoded public key environment variables to set for the operation. Format: KEY=@file.pem or KEY=VALUE.")
+	fs.StringVar(&testOutput, "output", "", "Print the result as a document instead of text. Accepted values: 'json', 'yaml'. Operations print the full response of the app and the duration, suites print a summary of the steps.")
+	fs.StringVar(&testJUnit, "junit", "", "Path to write a JUnit XML report of the --suite run to.")
+	fs.StringVar(&testSummary, "summary", "", "Path to write the summary of the --suite run to, as printed by --output. The summary is written as YAML when the path ends with .yaml or .yml, and as JSON otherwise.")
+	fs.BoolVar(&testSnapshot, "snapshot", false, "Compare the response with its snapshot in the testdata/snapshots directory of the app, and fail when they differ. Missing snapshots are stored. With --suite, the response of each step is compared.")
+	fs.BoolVar(&testUpdateSnapshots, "update-snapshots", false, "Replace the snapshots which differ from the responses. Implies --snapshot.")
+	fs.StringVar(&testRecord, "record", "", "Send the outbound HTTP requests of the app through a local proxy, and record them with their responses to a cassette file. Sensitive headers, such as Authorization, query parameters such as sig or api_key, and JSON or form body fields such as access_token or client_secret are redacted. Other secrets are written as they are: check the cassette before committing it, and use --redact-query and --redact-field.")
+	fs.StringArrayVar(&testRedactQuery, "redact-query", nil, "A query parameter whose values are redacted from the cassette, on top of the default ones. Requests are redacted alike to be replayed.")
+	fs.StringArrayVar(&testRedactFields, "redact-field", nil, "A field of JSON or form bodies whose values are redacted from the cassette at any depth, on top of the default ones. Requests are redacted alike to be replayed.")
+	fs.StringVar(&testReplay, "replay", "", "Answer the outbound HTTP requests of the app with the responses of a cassette file recorded with --record, without reaching the network. Requests missing from the cassette fail the test.")
+	fs.StringArrayVar(&testSnapshotIgnore, "snapshot-ignore", nil, "The path of a volatile value to ignore in snapshots, such as $.resource.external_id. Wildcards match any key or array item ($.resources[*].external_id), and $.. matches a key at any depth ($..external_id). Suites add the paths of their snapshot_ignore list.")
+}
+
+// outputRunnerOptions returns the options of the runner of the app for the
+// --output of a command. The document of --output is the only thing printed
+// to stdout, so the logs of the app are printed to stderr.
+func outputRunnerOptions(cmd *cobra.Command, output string) []runner.Option {
+	if output == "" {
+		return nil
+	}
+
+	return []runner.Option{runner.WithOutput(cmd.ErrOrStderr(), cmd.ErrOrStderr())}
+}
 
-	testCmd.Flags().StringVar(&testProjectID, "project-id", "", "The project ID to use for the operation. If not specified, a random one will be generated.")
-	testCmd.Flags().StringVar(&testDatasourceInput, "datasource-input", "", "The datasource input for the 'list' operation.")
+// addRequestFlags adds the flags of the environment and the metadata of the
+// requests of the commands sending them like app test.
+func addRequestFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVar(&testEnvironmentVariables, "env", nil, "Environment variables to set for the requests. Format: KEY=VALUE.")
+	fs.StringVar(&testEnvFile, "env-file", "", "A dotenv file of environment variables to set for the requests, as accepted by app test.")
+	fs.StringVar(&testMetadataFile, "metadata", "", "A YAML file with the metadata of the requests, as accepted by app test.")
+	fs.StringVar(&testProjectID, "project-id", "", "The project ID to use for the requests. If not specified, the project ID of the profile is used, or a random one is generated.")
 }
 
 func testRunE(cmd *cobra.Command, args []string) error {
+	return runTest(cmd, args)
+}
+
+// runTest runs the test of the app, whose runner gets the options on top of
+// the ones of the flags.
+func runTest(cmd *cobra.Command, args []string, extraRunnerOpts ...runner.Option) (err error) {
 	id, version, err := splitAppVersion(args[0])
 	if err != nil {
 		return err
@@ -69,6 +208,131 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("app version %s:%s not found in config", id, version)
 	}
 
+	// ListResourcesRequest and Resource have no field for these yet, so they
+	// cannot reach the app.
+	if testDatasourceInput != "" {
+		return errors.New("--datasource-input is not supported yet: the app protocol has no datasource input field in ListResourcesRequest")
+	}
+	if testParentExternalId != "" {
+		return errors.New("--parent-external-id is not supported yet: the app protocol has no parent external ID field in Resource")
+	}
+
+	if testMaxPages < 0 {
+		return errors.New("--max-pages must not be negative")
+	}
+
+	switch testOutput {
+	case "", outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid --output %q. Accepted values: %s, %s", testOutput, outputJSON, outputYAML)
+	}
+
+	if testJUnit != "" && testSuite == "" {
+		return errors.New("--junit can only be used with --suite")
+	}
+	if testSummary != "" && testSuite == "" {
+		return errors.New("--summary can only be used with --suite")
+	}
+
+	if testUpdateSnapshots {
+		testSnapshot = true
+	}
+	if !testSnapshot && (testSnapshotName != "" || len(testSnapshotIgnore) > 0) {
+		return errors.New("--snapshot-name and --snapshot-ignore can only be used with --snapshot")
+	}
+	if testSnapshotName != "" && testSuite != "" {
+		return errors.New("--snapshot-name cannot be used with --suite: steps are named after their scenario and name")
+	}
+	for _, p := range testSnapshotIgnore {
+		if err := snapshot.ValidatePath(p); err != nil {
+			return fmt.Errorf("--snapshot-ignore: %w", err)
+		}
+	}
+
+	var runnerOpts []runner.Option
+	redact := cassette.Redaction{QueryParams: testRedactQuery, BodyFields: testRedactFields}
+	switch {
+	case testRecord != "" && testReplay != "":
+		return errors.New("--record and --replay cannot be used together")
+	case testRecord != "":
+		runnerOpts = append(runnerOpts, runner.WithCassette(cassette.Options{Mode: cassette.ModeRecord, Path: testRecord, Redact: redact}))
+	case testReplay != "":
+		runnerOpts = append(runnerOpts, runner.WithCassette(cassette.Options{Mode: cassette.ModeReplay, Path: testReplay, Redact: redact}))
+	}
+
+	var coverDir string
+	if testCoverProfile != "" || testCoverDir != "" {
+		testCover = true
+	}
+	if testCover {
+		coverDir = testCoverDir
+		if coverDir == "" {
+			coverDir, err = os.MkdirTemp("", "tempest-cover-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(coverDir)
+		} else if err := os.MkdirAll(coverDir, 0o755); err != nil {
+			return err
+		}
+
+		runnerOpts = append(runnerOpts, runner.WithCoverage(coverDir))
+	}
+	runnerOpts = append(runnerOpts, extraRunnerOpts...)
+
+	// Load the suite before starting the app, to report mistakes early.
+	var s *suite.Suite
+	if testSuite != "" {
+		s, err = suite.Load(testSuite)
+		if err != nil {
+			return fmt.Errorf("load suite: %w", err)
+		}
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	if testSnapshot {
+		dir := snapshotDir(cfgDir, appVersion)
+		if testSuite != "" {
+			// Suites of an app have their own snapshot directories.
+			dir = filepath.Join(dir, strings.TrimSuffix(filepath.Base(testSuite), filepath.Ext(testSuite)))
+		}
+
+		testSnapshots = &snapshot.Store{
+			Dir:    dir,
+			Ignore: testSnapshotIgnore,
+			Update: testUpdateSnapshots,
+		}
+	}
+
+	testState, err = state.Load(state.Path(cfgDir, id, version))
+	if err != nil {
+		return fmt.Errorf("load state: %w", err)
+	}
+
+	if err := resolveAlias(); err != nil {
+		return err
+	}
+
+	if testAs != "" {
+		if testOperation != "create" || testAction != "" || testSuite != "" {
+			return errors.New("--as can only be used with --operation create")
+		}
+		// The alias is checked before the resource is created, so that it
+		// is not left untracked.
+		if r := testState.Lookup(testAs); r != nil {
+			return fmt.Errorf("alias %s is already used by %s resource %s. Delete it or choose another alias", testAs, r.Type, r.ExternalID)
+		}
+	}
+
 	if !appPreserveBuildDir {
 		err := generateBuildDir(cfg, cfgDir, id, version)
 		if err != nil {
@@ -76,11 +340,23 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion)
+	runnerOpts = append(runnerOpts, outputRunnerOptions(cmd, testOutput)...)
+
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion, runnerOpts...)
 	if err != nil {
 		return fmt.Errorf("start app: %w", err)
 	}
 	defer cancel()
+	defer func() {
+		err = errors.Join(err, closeCassette(cmd, runner.Cassette))
+	}()
+	defer func() {
+		if coverDir != "" {
+			// The app writes its counters once stopped.
+			cancel()
+			err = errors.Join(err, reportCoverage(cmd, coverDir, cfgDir))
+		}
+	}()
 
 	des, err := runner.Client.Describe(context.TODO(), connect.NewRequest(&appv1.DescribeRequest{}))
 	if err != nil {
@@ -88,7 +364,9 @@ func testRunE(cmd *cobra.Command, args []string) error {
 	}
 
 	typesToOperations := make(map[string][]string)
+	definitions := make(map[string]*appv1.ResourceDefinition)
 	for _, r := range des.Msg.ResourceDefinitions {
+		definitions[r.Type] = r
 		typesToOperations[r.Type] = []string{}
 
 		if r.ListSupported {
@@ -106,6 +384,13 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		if r.DeleteSupported {
 			typesToOperations[r.Type] = append(typesToOperations[r.Type], "delete")
 		}
+		if r.HealthcheckSupported {
+			typesToOperations[r.Type] = append(typesToOperations[r.Type], "healthcheck")
+		}
+	}
+
+	if s != nil {
+		return runTestSuite(cmd, runner.Client, args[0], s, definitions, typesToOperations, metadata, ev)
 	}
 
 	if testType == "" {
@@ -116,25 +401,27 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if testAction != "" {
+		return runTestAction(cmd, runner.Client, definitions[testType], metadata, ev)
+	}
+
+	// Actions are run with --action, rather than --operation.
+	availableOperations := slices.Clone(typesToOperations[testType])
+	for _, a := range definitions[testType].Actions {
+		availableOperations = append(availableOperations, "--action "+a.Name)
+	}
+
 	if testOperation == "" {
-		availableOperations := typesToOperations[testType]
 		return fmt.Errorf("operation is required. Supported operations for %s: %s", testType, strings.Join(availableOperations, ", "))
 	} else if !slices.Contains(typesToOperations[testType], testOperation) {
-		return fmt.Errorf("operation %s not found for type %s. Supported operations: %s", testOperation, testType, strings.Join(typesToOperations[testType], ", "))
+		return fmt.Errorf("operation %s not found for type %s. Supported operations: %s", testOperation, testType, strings.Join(availableOperations, ", "))
 	}
 
-	ev := make([]*appv1.EnvironmentVariable, 0, len(testEnvironmentVariables))
-	for _, e := range testEnvironmentVariables {
-		k, v, ok := strings.Cut(e, "=")
-		if !ok {
-			return fmt.Errorf("invalid environment variable: %s", e)
-		}
-
-		ev = append(ev, &appv1.EnvironmentVariable{
-			Key:   k,
-			Value: v,
-			Type:  appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_VAR,
-		})
+	// Like app serve, an invalid properties schema only skips the validation
+	// of the output.
+	propertiesSchema, err := propertiesSchemaFor(definitions[testType])
+	if err != nil {
+		cmd.Printf("⚠️  The output will not be validated: %s\n", err)
 	}
 
 	switch testOperation {
@@ -144,34 +431,30 @@ func testRunE(cmd *cobra.Command, args []string) error {
 			Resource: &appv1.Resource{
 				Type: testType,
 			},
-			Metadata: &appv1.Metadata{
-				ProjectId: projectID(testProjectID),
-			},
+			Metadata:             metadata,
 			EnvironmentVariables: ev,
 		}
 
-		if testInput != "" {
-			var input map[string]any
-			err := json.Unmarshal([]byte(testInput), &input)
-			if err != nil {
-				// TODO: provide a better error message, including the required fields from the schema
-				return fmt.Errorf("unmarshal input: %w", err)
-			}
-
-			s, err := structpb.NewStruct(input)
-			if err != nil {
-				return fmt.Errorf("new struct: %w", err)
-			}
-
-			req.Input = s
+		req.Input, err = operationInput(cmd, definitions[testType].CreateInputSchema)
+		if err != nil {
+			return err
 		}
 
+		start := time.Now()
 		res, err := runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(req))
 		if err != nil {
 			return fmt.Errorf("execute resource operation: %w", err)
 		}
+		trackResource(cmd, testOperation, testType, res.Msg.Resource.GetExternalId())
+
+		if testOutput != "" {
+			return writeTestResult(cmd, newTestResult(start), res.Msg, propertiesSchema, res.Msg.Resource)
+		}
 
 		cmd.Println("\nResource created with ID:", res.Msg.Resource.GetExternalId())
+		if testAs != "" {
+			cmd.Println("Alias:", testAs)
+		}
 
 		j, err := json.MarshalIndent(res.Msg.Resource.Properties, "", "  ")
 		if err != nil {
@@ -179,6 +462,8 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		}
 		cmd.Printf("Properties:\n%s\n", pretty.Color(j, nil))
 
+		return errors.Join(checkOutput(cmd, propertiesSchema, res.Msg.Resource), printSnapshot(cmd, res.Msg))
+
 	case "update":
 		if testExternalID == "" {
 			return fmt.Errorf("external ID (--external-id) is required for update operation")
@@ -190,33 +475,25 @@ func testRunE(cmd *cobra.Command, args []string) error {
 				Type:       testType,
 				ExternalId: testExternalID,
 			},
-			Metadata: &appv1.Metadata{
-				ProjectId: projectID(testProjectID),
-			},
+			Metadata:             metadata,
 			EnvironmentVariables: ev,
 		}
 
-		if testInput != "" {
-			var input map[string]any
-			err := json.Unmarshal([]byte(testInput), &input)
-			if err != nil {
-				// TODO: provide a better error message, including the required fields from the schema
-				return fmt.Errorf("unmarshal input: %w", err)
-			}
-
-			s, err := structpb.NewStruct(input)
-			if err != nil {
-				return fmt.Errorf("new struct: %w", err)
-			}
-
-			req.Input = s
+		req.Input, err = operationInput(cmd, definitions[testType].UpdateInputSchema)
+		if err != nil {
+			return err
 		}
 
+		start := time.Now()
 		res, err := runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(req))
 		if err != nil {
 			return fmt.Errorf("execute resource operation: %w", err)
 		}
 
+		if testOutput != "" {
+			return writeTestResult(cmd, newTestResult(start), res.Msg, propertiesSchema, res.Msg.Resource)
+		}
+
 		cmd.Println("\nResource updated with ID:", res.Msg.Resource.GetExternalId())
 
 		j, err := json.MarshalIndent(res.Msg.Resource.Properties, "", "  ")
@@ -225,40 +502,52 @@ func testRunE(cmd *cobra.Command, args []string) error {
 		}
 		cmd.Printf("Properties:\n%s\n", pretty.Color(j, nil))
 
+		return errors.Join(checkOutput(cmd, propertiesSchema, res.Msg.Resource), printSnapshot(cmd, res.Msg))
+
 	case "delete":
 		if testExternalID == "" {
 			return fmt.Errorf("external ID (--external-id) is required for destroy operation")
 		}
 
+		start := time.Now()
 		res, err := runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
 			Operation: appv1.ResourceOperation_RESOURCE_OPERATION_DELETE,
 			Resource: &appv1.Resource{
 				Type:       testType,
 				ExternalId: testExternalID,
 			},
-			Metadata: &appv1.Metadata{
-				ProjectId: projectID(testProjectID),
-			},
+			Metadata:             metadata,
 			EnvironmentVariables: ev,
 		}))
 		if err != nil {
 			return fmt.Errorf("execute resource operation: %w", err)
 		}
+		trackResource(cmd, testOperation, testType, testExternalID)
+
+		if testOutput != "" {
+			return writeTestResult(cmd, newTestResult(start), res.Msg, nil)
+		}
 
 		cmd.Println("Resource deleted with ID:", res.Msg.Resource.GetExternalId())
 
+		return printSnapshot(cmd, res.Msg)
+
 	case "list":
-		var next string
+		next := testNext
 		var resources []*appv1.Resource
-		for {
+		var pages int
+		start := time.Now()
+		maxPages := testMaxPages
+		if maxPages == 0 {
+			maxPages = testMaxListPages
+		}
+		for pages = 1; ; pages++ {
 			req := &appv1.ListResourcesRequest{
 				Resource: &appv1.Resource{
 					Type: testType,
 				},
-				Metadata: &appv1.Metadata{
-					ProjectId: projectID(testProjectID),
-				},
-				Next: next,
+				Metadata: metadata,
+				Next:     next,
 			}
 
 			res, err := runner.Client.ListResources(context.TODO(), connect.NewRequest(req))
@@ -268,11 +557,21 @@ func testRunE(cmd *cobra.Command, args []string) error {
 
 			resources = append(resources, res.Msg.GetResources()...)
 
-			if res.Msg.Next == "" {
+			if res.Msg.Next != "" && res.Msg.Next == next {
+				return fmt.Errorf("list resources: the app returned the next page token %q of the page it was given", next)
+			}
+			next = res.Msg.Next
+			if next == "" || pages >= maxPages {
 				break
 			}
+		}
 
-			next = res.Msg.Next
+		if testOutput != "" {
+			// The pages are merged into a single response, with the token
+			// of the page after the last one fetched.
+			r := newTestResult(start)
+			r.Pages = pages
+			return writeTestResult(cmd, r, &appv1.ListResourcesResponse{Resources: resources, Next: next}, propertiesSchema, resources...)
 		}
 
 		cmd.Println("Resources:")
@@ -285,6 +584,12 @@ func testRunE(cmd *cobra.Command, args []string) error {
 			cmd.Println("\nExternal ID:", r.GetExternalId())
 			cmd.Printf("Properties:\n%s\n", pretty.Color(j, nil))
 		}
+
+		if next != "" {
+			cmd.Printf("\nMore resources are available. Next page token: %s\nContinue with: --next %s\n", next, shellQuote(next))
+		}
+
+		return errors.Join(checkOutput(cmd, propertiesSchema, resources...), printSnapshot(cmd, &appv1.ListResourcesResponse{Resources: resources, Next: next}))
 	case "read":
 		if testExternalID == "" {
 			return fmt.Errorf("external ID (--external-id) is required for get operation")
@@ -296,17 +601,20 @@ func testRunE(cmd *cobra.Command, args []string) error {
 				Type:       testType,
 				ExternalId: testExternalID,
 			},
-			Metadata: &appv1.Metadata{
-				ProjectId: projectID(testProjectID),
-			},
+			Metadata:             metadata,
 			EnvironmentVariables: ev,
 		}
 
+		start := time.Now()
 		res, err := runner.Client.ExecuteResourceOperation(context.TODO(), connect.NewRequest(req))
 		if err != nil {
 			return fmt.Errorf("get resource: %w", err)
 		}
 
+		if testOutput != "" {
+			return writeTestResult(cmd, newTestResult(start), res.Msg, propertiesSchema, res.Msg.Resource)
+		}
+
 		j, err := json.MarshalIndent(res.Msg.Resource.Properties, "", "  ")
 		if err != nil {
 			return fmt.Errorf("marshal resource properties: %w", err)
@@ -314,11 +622,1079 @@ func testRunE(cmd *cobra.Command, args []string) error {
 
 		cmd.Println("\nResource:", res.Msg.Resource.GetExternalId())
 		cmd.Printf("Properties:\n%s\n", pretty.Color(j, nil))
+
+		return errors.Join(checkOutput(cmd, propertiesSchema, res.Msg.Resource), printSnapshot(cmd, res.Msg))
+	case "healthcheck":
+		start := time.Now()
+		res, err := runner.Client.HealthCheck(context.TODO(), connect.NewRequest(&appv1.HealthCheckRequest{
+			Type: testType,
+		}))
+		if err != nil {
+			return fmt.Errorf("health check: %w", err)
+		}
+
+		if testOutput != "" {
+			return writeTestResult(cmd, newTestResult(start), res.Msg, nil)
+		}
+
+		var icon string
+		switch res.Msg.Status {
+		case appv1.HealthCheckStatus_HEALTH_CHECK_STATUS_HEALTHY:
+			icon = "✅"
+		case appv1.HealthCheckStatus_HEALTH_CHECK_STATUS_DEGRADED:
+			icon = "⚠️"
+		default:
+			icon = "❌"
+		}
+
+		cmd.Printf("\nHealth check status: %s %s\n", icon, appStatusToTempestStatus(res.Msg.Status))
+		if res.Msg.Message != "" {
+			cmd.Println("Message:", res.Msg.Message)
+		}
+
+		return printSnapshot(cmd, res.Msg)
+	}
+
+	return nil
+}
+
+// runTestAction executes the --action of the resource definition, and prints
+// its output.
+func runTestAction(cmd *cobra.Command, client appv1connect.AppServiceClient, rd *appv1.ResourceDefinition, metadata *appv1.Metadata, ev []*appv1.EnvironmentVariable) error {
+	if testOperation != "" {
+		return errors.New("--operation and --action cannot be used together")
+	}
+	if testExternalID == "" {
+		return fmt.Errorf("external ID (--external-id) is required for action %s", testAction)
+	}
+
+	i := slices.IndexFunc(rd.Actions, func(a *appv1.ActionDefinition) bool { return a.Name == testAction })
+	if i == -1 {
+		names := make([]string, 0, len(rd.Actions))
+		for _, a := range rd.Actions {
+			names = append(names, a.Name)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("action %s not found for type %s. The type has no actions", testAction, rd.Type)
+		}
+		return fmt.Errorf("action %s not found for type %s. Available actions: %s", testAction, rd.Type, strings.Join(names, ", "))
+	}
+	action := rd.Actions[i]
+
+	input, err := operationInput(cmd, action.InputSchema)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	res, err := client.ExecuteResourceAction(context.TODO(), connect.NewRequest(&appv1.ExecuteResourceActionRequest{
+		Resource: &appv1.Resource{
+			Type:       rd.Type,
+			ExternalId: testExternalID,
+		},
+		Action:               action.Name,
+		Input:                input,
+		Metadata:             metadata,
+		EnvironmentVariables: ev,
+	}))
+	if err != nil {
+		return fmt.Errorf("execute resource action: %w", err)
+	}
+	duration := time.Since(start)
+
+	var violations []string
+	if action.OutputSchema != nil {
+		sch, err := schema.New(action.OutputSchema.AsMap())
+		if err != nil {
+			return fmt.Errorf("output schema of action %s: %w", action.Name, err)
+		}
+
+		var ve *schema.ValidationError
+		if err := sch.Validate(res.Msg.Output.AsMap()); errors.As(err, &ve) {
+			for _, v := range ve.Violations {
+				violations = append(violations, v.String())
+			}
+		} else if err != nil {
+			return fmt.Errorf("validate output: %w", err)
+		}
+	}
+
+	// A response which does not match its snapshot fails the command, after
+	// the output is reported.
+	var snapshotErr error
+	if testOutput != "" {
+		snapshotErr = printTestResult(cmd, &testResult{
+			Action:     action.Name,
+			Type:       rd.Type,
+			DurationMS: float64(duration.Microseconds()) / 1000,
+			Violations: violations,
+		}, res.Msg)
+	} else {
+		j, err := json.MarshalIndent(res.Msg.Output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal output: %w", err)
+		}
+
+		cmd.Println("\nAction executed:", action.Name)
+		cmd.Printf("Output:\n%s\n", pretty.Color(j, nil))
+
+		if len(violations) > 0 {
+			cmd.Println("\n❌ The output does not match the output schema:")
+			for _, v := range violations {
+				cmd.Printf("  - %s\n", v)
+			}
+		}
+
+		snapshotErr = printSnapshot(cmd, res.Msg)
+	}
+
+	if len(violations) > 0 {
+		cmd.SilenceUsage = true
+		return errors.Join(errors.New("the app returned an output that does not match its output schema"), snapshotErr)
+	}
+
+	return snapshotErr
+}
+
+// operationInput parses --input, and validates it against the input schema of
+// the operation. When the input is invalid, the error lists the fields of the
+// schema. Without --input on a terminal, the input is prompted for.
+func operationInput(cmd *cobra.Command, inputSchema *structpb.Struct) (*structpb.Struct, error) {
+	var sch *schema.Schema
+	if inputSchema != nil {
+		var err error
+		sch, err = schema.New(inputSchema.AsMap())
+		if err != nil {
+			return nil, fmt.Errorf("input schema: %w", err)
+		}
+	}
+
+	input := map[string]any{}
+	switch {
+	case testInput != "" || len(testSet) > 0:
+		if testInput != "" {
+			var err error
+			input, err = readInput(cmd.InOrStdin(), testInput)
+			if err != nil {
+				return nil, inputError(fmt.Sprintf("invalid input: %s", err), inputSchema)
+			}
+		}
+
+		for _, set := range testSet {
+			if err := setInputValue(input, set); err != nil {
+				return nil, inputError(err.Error(), inputSchema)
+			}
+		}
+	case sch != nil && term.IsTerminal(int(syscall.Stdin)):
+		cmd.Println("Enter the input of the operation. Leave optional values empty to skip them.")
+		cmd.Println()
+
+		var err error
+		input, err = schema.NewPrompter(cmd.InOrStdin(), cmd.OutOrStderr()).Prompt(sch)
+		if err != nil {
+			return nil, fmt.Errorf("prompt input: %w", err)
+		}
+
+		b, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("marshal input: %w", err)
+		}
+		cmd.Printf("\nTo run this test again without prompts:\n  %s\n", commandWithInput(string(b)))
+	}
+
+	if sch != nil {
+		var ve *schema.ValidationError
+		if err := sch.Validate(input); errors.As(err, &ve) {
+			msg := strings.Builder{}
+			msg.WriteString("invalid input:")
+			for _, v := range ve.Violations {
+				msg.WriteString("\n  - " + v.String())
+			}
+			return nil, inputError(msg.String(), inputSchema)
+		} else if err != nil {
+			return nil, fmt.Errorf("validate input: %w", err)
+		}
+	}
+
+	s, err := structpb.NewStruct(input)
+	if err != nil {
+		return nil, fmt.Errorf("new struct: %w", err)
+	}
+
+	return s, nil
+}
+
+// readInput reads the --input value: inline JSON, a JSON or YAML file
+// prefixed with @, or - to read JSON or YAML from stdin.
+func readInput(stdin io.Reader, value string) (map[string]any, error) {
+	var b []byte
+	var err error
+	format := "json"
+	switch {
+	case value == "-":
+		b, err = io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+		// YAML is a superset of JSON.
+		format = "yaml"
+	case strings.HasPrefix(value, "@"):
+		path := value[1:]
+		b, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+			format = "yaml"
+		}
+	default:
+		b = []byte(value)
+	}
+
+	var input map[string]any
+	if format == "yaml" {
+		if err := yaml.Unmarshal(b, &input); err != nil {
+			return nil, err
+		}
+		// Convert YAML types, such as integers, to their JSON counterparts.
+		j, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		input = nil
+		b = j
+	}
+
+	if err := json.Unmarshal(b, &input); err != nil {
+		return nil, err
+	}
+
+	if input == nil {
+		input = map[string]any{}
+	}
+
+	return input, nil
+}
+
+// setInputValue applies a --set path.to.field=value expression to input.
+// Intermediate objects are created as needed, and numeric path segments
+// index into existing arrays.
+func setInputValue(input map[string]any, expr string) error {
+	path, raw, ok := strings.Cut(expr, "=")
+	if !ok || path == "" {
+		return fmt.Errorf("invalid --set %q. Format: path.to.field=value", expr)
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		value = raw
+	}
+
+	keys := strings.Split(path, ".")
+	var current any = input
+	for i, key := range keys {
+		last := i == len(keys)-1
+
+		switch c := current.(type) {
+		case map[string]any:
+			if last {
+				c[key] = value
+				return nil
+			}
+			next, ok := c[key]
+			if !ok {
+				next = map[string]any{}
+				c[key] = next
+			}
+			current = next
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return fmt.Errorf("invalid --set %q: %s is not an index of %s", expr, key, strings.Join(keys[:i], "."))
+			}
+			if last {
+				c[idx] = value
+				return nil
+			}
+			current = c[idx]
+		default:
+			return fmt.Errorf("invalid --set %q: %s is not an object", expr, strings.Join(keys[:i], "."))
+		}
 	}
 
 	return nil
 }
 
+// testEnvironment returns the environment variables of the operation, from
+// --env-file, --env and the typed --env-* flags. PEM encoded values are
+// validated.
+func testEnvironment() ([]*appv1.EnvironmentVariable, error) {
+	var ev []*appv1.EnvironmentVariable
+	set := func(v *appv1.EnvironmentVariable) error {
+		if check, ok := pemChecks[v.Type]; ok {
+			if err := check(v.Value); err != nil {
+				return fmt.Errorf("environment variable %s: invalid %s: %w", v.Key, environmentVariableTypeName(v.Type), err)
+			}
+		}
+
+		ev = slices.DeleteFunc(ev, func(e *appv1.EnvironmentVariable) bool { return e.Key == v.Key })
+		ev = append(ev, v)
+		return nil
+	}
+
+	if testEnvFile != "" {
+		entries, err := dotenv.Read(testEnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("read env file: %w", err)
+		}
+
+		for _, e := range entries {
+			t := appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_VAR
+			key := e.Key
+			if typeName, k, ok := strings.Cut(e.Key, ":"); ok {
+				var known bool
+				t, known = environmentVariableTypes[typeName]
+				if !known {
+					return nil, fmt.Errorf("env file %s: invalid type %q for %s. Accepted types: 'var', 'secret', 'certificate', 'private_key', 'public_key'", testEnvFile, typeName, k)
+				}
+				key = k
+			}
+
+			err := set(&appv1.EnvironmentVariable{
+				Key:   key,
+				Value: e.Value,
+				Type:  t,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, e := range testEnvironmentVariables {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid environment variable: %s", e)
+		}
+
+		err := set(&appv1.EnvironmentVariable{
+			Key:   k,
+			Value: v,
+			Type:  appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_VAR,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	typed := []struct {
+		values []string
+		t      appv1.EnvironmentVariableType
+	}{
+		{testEnvSecrets, appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_SECRET},
+		{testEnvCertificates, appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_CERTIFICATE},
+		{testEnvPrivateKeys, appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PRIVATE_KEY},
+		{testEnvPublicKeys, appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PUBLIC_KEY},
+	}
+	for _, tv := range typed {
+		for _, e := range tv.values {
+			k, v, ok := strings.Cut(e, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid environment variable: %s", e)
+			}
+
+			// Values starting with @ are read from a file.
+			if path, ok := strings.CutPrefix(v, "@"); ok {
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return nil, fmt.Errorf("environment variable %s: %w", k, err)
+				}
+				v = string(b)
+			}
+
+			err := set(&appv1.EnvironmentVariable{
+				Key:   k,
+				Value: v,
+				Type:  tv.t,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ev, nil
+}
+
+var environmentVariableTypes = map[string]appv1.EnvironmentVariableType{
+	"var":         appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_VAR,
+	"secret":      appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_SECRET,
+	"certificate": appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_CERTIFICATE,
+	"private_key": appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PRIVATE_KEY,
+	"public_key":  appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PUBLIC_KEY,
+}
+
+// pemChecks validate the values of PEM encoded environment variables.
+var pemChecks = map[appv1.EnvironmentVariableType]func(string) error{
+	appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_CERTIFICATE: pemcheck.Certificate,
+	appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PRIVATE_KEY: pemcheck.PrivateKey,
+	appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_PUBLIC_KEY:  pemcheck.PublicKey,
+}
+
+func environmentVariableTypeName(t appv1.EnvironmentVariableType) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(t.String(), "ENVIRONMENT_VARIABLE_TYPE_")), "_", " ")
+}
+
+// testOwner is an author or owner in the --metadata file.
+type testOwner struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	// "user" or "team".
+	Type string `yaml:"type"`
+}
+
+func (o *testOwner) toProto() (*appv1.Owner, error) {
+	owner := &appv1.Owner{
+		Name:  o.Name,
+		Email: o.Email,
+	}
+
+	switch o.Type {
+	case "user":
+		owner.Type = appv1.OwnerType_OWNER_TYPE_USER
+	case "team":
+		owner.Type = appv1.OwnerType_OWNER_TYPE_TEAM
+	case "":
+	default:
+		return nil, fmt.Errorf("invalid owner type %q. Accepted values: 'user', 'team'", o.Type)
+	}
+
+	return owner, nil
+}
+
+// testMetadata returns the metadata of the operation, read from --metadata.
+// --project-id takes precedence over the project ID of the file, and a random
+// project ID is generated when neither is set.
+func testMetadata() (*appv1.Metadata, error) {
+	var file struct {
+		ProjectID   string      `yaml:"project_id"`
+		ProjectName string      `yaml:"project_name"`
+		Author      *testOwner  `yaml:"author"`
+		Owners      []testOwner `yaml:"owners"`
+	}
+
+	if testMetadataFile != "" {
+		f, err := os.Open(testMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("read metadata: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+
+		decoder := yaml.NewDecoder(f)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read metadata %s: %w", testMetadataFile, err)
+		}
+	}
+
+	metadata := &appv1.Metadata{
+		ProjectId:   file.ProjectID,
+		ProjectName: file.ProjectName,
+	}
+	switch {
+	case testProjectID != "":
+		metadata.ProjectId = testProjectID
+	case metadata.ProjectId == "":
+		metadata.ProjectId = projectID(defaultProjectID)
+	}
+
+	if file.Author != nil {
+		author, err := file.Author.toProto()
+		if err != nil {
+			return nil, fmt.Errorf("metadata author: %w", err)
+		}
+		metadata.Author = author
+	}
+
+	for _, o := range file.Owners {
+		owner, err := o.toProto()
+		if err != nil {
+			return nil, fmt.Errorf("metadata owners: %w", err)
+		}
+		metadata.Owners = append(metadata.Owners, owner)
+	}
+
+	return metadata, nil
+}
+
+// commandWithInput returns the command line of the current invocation, with
+// the given --input.
+func commandWithInput(input string) string {
+	args := []string{filepath.Base(os.Args[0])}
+	args = append(args, os.Args[1:]...)
+	args = append(args, "--input", input)
+
+	for i, a := range args {
+		args[i] = shellQuote(a)
+	}
+
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for POSIX shells, when needed.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@%+,", r)
+	}) == -1 {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// inputError returns an error with msg, followed by the required and optional
+// fields of the input schema.
+func inputError(msg string, inputSchema *structpb.Struct) error {
+	if inputSchema == nil {
+		return errors.New(msg)
+	}
+
+	sch, err := schema.New(inputSchema.AsMap())
+	if err != nil {
+		return errors.New(msg)
+	}
+
+	var required, optional strings.Builder
+	for _, f := range sch.Fields() {
+		line := "\n  - " + f.Name
+		if f.Type != "" {
+			line += " (" + f.Type + ")"
+		}
+		if f.Description != "" {
+			line += ": " + f.Description
+		} else if f.Title != "" {
+			line += ": " + f.Title
+		}
+
+		if f.Required {
+			required.WriteString(line)
+		} else {
+			optional.WriteString(line)
+		}
+	}
+
+	if required.Len() > 0 {
+		msg += "\n\nRequired fields:" + required.String()
+	}
+	if optional.Len() > 0 {
+		msg += "\n\nOptional fields:" + optional.String()
+	}
+
+	return errors.New(msg)
+}
+
+// propertiesSchemaFor compiles the properties schema of the resource
+// definition. It returns nil when the definition has no properties schema.
+func propertiesSchemaFor(rd *appv1.ResourceDefinition) (*schema.Schema, error) {
+	if rd.GetPropertiesSchema() == nil {
+		return nil, nil
+	}
+
+	sch, err := schema.New(rd.PropertiesSchema.AsMap())
+	if err != nil {
+		return nil, fmt.Errorf("properties schema of %s: %w", rd.Type, err)
+	}
+
+	return sch, nil
+}
+
+// propertiesViolations validates the properties of the resources against the
+// properties schema. Violations are prefixed with the external ID of the
+// resource when validating several resources.
+func propertiesViolations(propertiesSchema *schema.Schema, resources ...*appv1.Resource) ([]string, error) {
+	if propertiesSchema == nil {
+		return nil, nil
+	}
+
+	var violations []string
+	for _, r := range resources {
+		err := propertiesSchema.Validate(r.GetProperties().AsMap())
+
+		var ve *schema.ValidationError
+		if !errors.As(err, &ve) {
+			if err != nil {
+				return nil, fmt.Errorf("validate properties: %w", err)
+			}
+			continue
+		}
+
+		for _, v := range ve.Violations {
+			if len(resources) > 1 {
+				violations = append(violations, fmt.Sprintf("resource %s: %s", r.GetExternalId(), v))
+				continue
+			}
+			violations = append(violations, v.String())
+		}
+	}
+
+	return violations, nil
+}
+
+// checkOutput reports the properties of the returned resources that do not
+// match the properties schema, and fails when there are any.
+func checkOutput(cmd *cobra.Command, propertiesSchema *schema.Schema, resources ...*appv1.Resource) error {
+	violations, err := propertiesViolations(propertiesSchema, resources...)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	cmd.Println("\n❌ The properties do not match the properties schema:")
+	for _, v := range violations {
+		cmd.Printf("  - %s\n", v)
+	}
+
+	cmd.SilenceUsage = true
+	return errors.New("the app returned properties that do not match its properties schema")
+}
+
+// testResult is the document printed by --output.
+type testResult struct {
+	Operation  string           `json:"operation,omitempty"`
+	Action     string           `json:"action,omitempty"`
+	Type       string           `json:"type"`
+	DurationMS float64          `json:"duration_ms"`
+	Pages      int              `json:"pages,omitempty"`
+	Response   json.RawMessage  `json:"response"`
+	Violations []string         `json:"violations,omitempty"`
+	Snapshot   *snapshot.Result `json:"snapshot,omitempty"`
+}
+
+// newTestResult returns the result of the tested operation, which started at
+// start.
+func newTestResult(start time.Time) *testResult {
+	return &testResult{
+		Operation:  testOperation,
+		Type:       testType,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+}
+
+// writeTestResult prints the response of the operation in the --output format,
+// and fails like checkOutput when the properties of the returned resources do
+// not match the properties schema. Violations are part of the document.
+func writeTestResult(cmd *cobra.Command, r *testResult, msg proto.Message, propertiesSchema *schema.Schema, resources ...*appv1.Resource) error {
+	violations, err := propertiesViolations(propertiesSchema, resources...)
+	if err != nil {
+		return err
+	}
+	r.Violations = violations
+
+	err = printTestResult(cmd, r, msg)
+
+	if len(violations) > 0 {
+		cmd.SilenceUsage = true
+		return errors.Join(errors.New("the app returned properties that do not match its properties schema"), err)
+	}
+
+	return err
+}
+
+// printTestResult prints r to stdout in the --output format, with msg, the
+// response of the app, as its response. With --snapshot, the comparison of
+// the response with its snapshot is part of the document, and a mismatch is
+// returned as an error.
+func printTestResult(cmd *cobra.Command, r *testResult, msg proto.Message) error {
+	var err error
+	r.Response, err = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	r.Snapshot, err = checkSnapshot(r.Response)
+	if err != nil {
+		return err
+	}
+
+	if err := printDocument(cmd.OutOrStdout(), testOutput, r); err != nil {
+		return err
+	}
+
+	return snapshotMismatch(cmd, r.Snapshot)
+}
+
+// resolveAlias replaces an --external-id which is the alias of a resource in
+// the state with the external ID of the resource. --type defaults to the type
+// of the resource.
+func resolveAlias() error {
+	r := testState.Lookup(testExternalID)
+	if r == nil {
+		return nil
+	}
+
+	if testType == "" {
+		testType = r.Type
+	} else if testType != r.Type {
+		return fmt.Errorf("alias %s is a resource of type %s, not %s", testExternalID, r.Type, testType)
+	}
+	testExternalID = r.ExternalID
+
+	return nil
+}
+
+// trackResource records a created resource in the state, with the --as alias,
+// and forgets a deleted one. Failing to write the state does not fail the
+// test.
+func trackResource(cmd *cobra.Command, operation, resourceType, externalID string) {
+	var err error
+	switch operation {
+	case "create":
+		err = testState.Add(resourceType, externalID, testAs)
+	case "delete":
+		if !testState.Remove(resourceType, externalID) {
+			return
+		}
+	default:
+		return
+	}
+
+	if err == nil {
+		err = testState.Save()
+	}
+	if err != nil {
+		cmd.Printf("⚠️  Failed to update the state %s: %s\n", testState.Path(), err)
+	}
+}
+
+// buildModule is the module of the build directory, see generateBuildDir.
+const buildModule = "tempestappserver"
+
+// reportCoverage prints the coverage of the apps from the counters written to
+// dir, and writes the --coverprofile.
+func reportCoverage(cmd *cobra.Command, dir, cfgDir string) error {
+	profile, err := cover.Profile(dir)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("coverage profile: %w", err)
+	}
+
+	// Only the apps are of interest among the packages of the build directory.
+	profile = cover.Filter(profile, buildModule+"/apps/")
+
+	// The files of the apps are referred to by the module of the apps when
+	// there is one, which go tool cover resolves, unlike the build directory.
+	if module := cover.ModulePath(cfgDir); module != "" {
+		profile = cover.Rewrite(profile, buildModule, module)
+	}
+
+	summary, err := cover.Summarize(profile)
+	if err != nil {
+		return fmt.Errorf("coverage profile: %w", err)
+	}
+
+	cmd.Println("\n📊 Coverage:")
+	for _, p := range summary.Packages {
+		cmd.Printf("  %s: %.1f%% of statements\n", p.Path, p.Percent)
+	}
+	cmd.Printf("  total: %.1f%% of statements\n", summary.Percent)
+
+	if testCoverProfile != "" {
+		if err := os.WriteFile(testCoverProfile, profile, 0o644); err != nil {
+			return fmt.Errorf("write coverage profile: %w", err)
+		}
+		cmd.Printf("Coverage profile written to %s\n", testCoverProfile)
+	}
+
+	return nil
+}
+
+// closeCassette closes the proxy of the app, which writes the cassette when
+// recording. When replaying, it fails if requests of the app were not found in
+// the cassette.
+func closeCassette(cmd *cobra.Command, p *cassette.Proxy) error {
+	if p == nil {
+		return nil
+	}
+
+	if err := p.Close(); err != nil {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("close cassette: %w", err)
+	}
+
+	if testRecord != "" {
+		cmd.Printf("\n📼 Recorded %d interactions to %s\n", p.Interactions(), testRecord)
+		return nil
+	}
+
+	misses := p.Misses()
+	if len(misses) == 0 {
+		return nil
+	}
+
+	cmd.Println("\n❌ Requests of the app not found in the cassette:")
+	for _, m := range misses {
+		cmd.Printf("  - %s\n", m)
+	}
+
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d requests not found in cassette %s. Record it again with --record", len(misses), testReplay)
+}
+
+// snapshotDir returns the directory the snapshots of the app are stored in.
+func snapshotDir(cfgDir string, appVersion *config.AppVersion) string {
+	dir := appVersion.Path
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cfgDir, dir)
+	}
+
+	return filepath.Join(dir, "testdata", "snapshots")
+}
+
+// checkSnapshot compares the JSON response of the tested operation with its
+// snapshot. It returns nil without --snapshot.
+func checkSnapshot(response []byte) (*snapshot.Result, error) {
+	if testSnapshots == nil {
+		return nil, nil
+	}
+
+	name := testSnapshotName
+	if name == "" {
+		if testAction != "" {
+			name = testType + "/action-" + testAction
+		} else {
+			name = testType + "/" + testOperation
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal(response, &doc); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+
+	return testSnapshots.Check(name, doc)
+}
+
+// printSnapshot compares msg, the response of the tested operation, with its
+// snapshot, and prints the result. It returns an error when they differ.
+func printSnapshot(cmd *cobra.Command, msg proto.Message) error {
+	if testSnapshots == nil {
+		return nil
+	}
+
+	b, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	r, err := checkSnapshot(b)
+	if err != nil {
+		return err
+	}
+
+	switch r.Status {
+	case snapshot.StatusCreated:
+		cmd.Printf("\n📸 Snapshot stored: %s\n", r.Path)
+	case snapshot.StatusMatched:
+		cmd.Printf("\n📸 The response matches the snapshot %s\n", r.Path)
+	case snapshot.StatusUpdated:
+		cmd.Printf("\n📸 Snapshot updated: %s\n%s", r.Path, r.Diff)
+	case snapshot.StatusMismatch:
+		cmd.Printf("\n❌ The response does not match the snapshot %s:\n%s", r.Path, r.Diff)
+	}
+
+	return snapshotMismatch(cmd, r)
+}
+
+// snapshotMismatch returns an error when the response does not match its
+// snapshot.
+func snapshotMismatch(cmd *cobra.Command, r *snapshot.Result) error {
+	if !r.Mismatch() {
+		return nil
+	}
+
+	cmd.SilenceUsage = true
+	return fmt.Errorf("the response does not match the snapshot %s. Run with --update-snapshots to accept the changes", r.Path)
+}
+
+// printDocument writes v to w as indented JSON, or as YAML. YAML documents
+// are converted from the JSON encoding, to use the same field names and keep
+// their order.
+func printDocument(w io.Writer, format string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal output: %w", err)
+	}
+
+	if format == outputJSON {
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	// JSON is valid YAML, only its style needs to change.
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("convert output to YAML: %w", err)
+	}
+	resetStyle(&doc)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("marshal output: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+// resetStyle clears the flow and quoting styles of n and its children, so they
+// are encoded as block YAML. Strings that need quotes are still quoted.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+// runTestSuite runs the scenarios of the suite against the running app, and
+// prints a summary of the results.
+func runTestSuite(cmd *cobra.Command, client appv1connect.AppServiceClient, name string, s *suite.Suite, definitions map[string]*appv1.ResourceDefinition, typesToOperations map[string][]string, metadata *appv1.Metadata, ev []*appv1.EnvironmentVariable) error {
+	for _, sc := range s.Scenarios {
+		for _, st := range sc.Steps {
+			operations, ok := typesToOperations[st.Type]
+			if !ok {
+				return fmt.Errorf("%s: %s: type %s not found in app. Available types: %s", sc.Name, st.Name, st.Type, strings.Join(slices.Sorted(maps.Keys(typesToOperations)), ", "))
+			}
+			if !slices.Contains(operations, st.Operation) {
+				return fmt.Errorf("%s: %s: operation %s not found for type %s. Supported operations: %s", sc.Name, st.Name, st.Operation, st.Type, strings.Join(operations, ", "))
+			}
+		}
+	}
+
+	propertiesSchemas := make(map[string]*schema.Schema)
+	for t, rd := range definitions {
+		sch, err := propertiesSchemaFor(rd)
+		if err != nil {
+			cmd.Printf("⚠️  The output of %s will not be validated: %s\n", t, err)
+			continue
+		}
+		if sch != nil {
+			propertiesSchemas[t] = sch
+		}
+	}
+
+	// Failing steps are reported in the summary, the usage would only add noise.
+	cmd.SilenceUsage = true
+
+	result := suite.Run(context.TODO(), client, s, suite.Options{
+		Metadata:          metadata,
+		Env:               ev,
+		PropertiesSchemas: propertiesSchemas,
+		Snapshots:         testSnapshots,
+		Track: func(operation, resourceType, externalID string) {
+			trackResource(cmd, operation, resourceType, externalID)
+		},
+	})
+
+	if testJUnit != "" {
+		if err := writeJUnit(testJUnit, name, result); err != nil {
+			return err
+		}
+	}
+	if testSummary != "" {
+		if err := writeSummary(testSummary, result); err != nil {
+			return err
+		}
+	}
+
+	if testOutput != "" {
+		if err := printDocument(cmd.OutOrStdout(), testOutput, result.Summary()); err != nil {
+			return err
+		}
+	} else {
+		printSuiteResult(cmd, result)
+	}
+
+	if result.Failed() {
+		return errors.New("test suite failed")
+	}
+
+	return nil
+}
+
+// printSuiteResult prints the result of each step, and the number of steps
+// which passed, failed and were skipped.
+func printSuiteResult(cmd *cobra.Command, result *suite.Result) {
+	for _, sc := range result.Scenarios {
+		cmd.Printf("\n%s\n", sc.Name)
+		for _, st := range sc.Steps {
+			switch {
+			case st.Skipped:
+				cmd.Printf("  ⏭️  %s (skipped)\n", st.Name)
+			case st.Failed():
+				cmd.Printf("  ❌ %s (%s)\n", st.Name, st.Duration.Round(time.Millisecond))
+				if st.Err != nil {
+					cmd.Printf("      %s\n", st.Err)
+				}
+				for _, f := range st.Failures {
+					// Snapshot mismatches span several lines.
+					cmd.Printf("      %s\n", strings.ReplaceAll(strings.TrimSuffix(f, "\n"), "\n", "\n      "))
+				}
+			default:
+				cmd.Printf("  ✅ %s (%s)\n", st.Name, st.Duration.Round(time.Millisecond))
+			}
+
+			switch st.Snapshot.GetStatus() {
+			case snapshot.StatusCreated:
+				cmd.Printf("      📸 snapshot stored: %s\n", st.Snapshot.Path)
+			case snapshot.StatusUpdated:
+				cmd.Printf("      📸 snapshot updated: %s\n", st.Snapshot.Path)
+			}
+		}
+	}
+
+	passed, failed, skipped := result.Counts()
+	cmd.Printf("\n%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+}
+
+// writeJUnit writes the JUnit XML report of the suite run to path.
+func writeJUnit(path, name string, result *suite.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create JUnit report: %w", err)
+	}
+
+	if err := result.WriteJUnit(f, name); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write JUnit report: %w", err)
+	}
+
+	return f.Close()
+}
+
+// writeSummary writes the summary of the suite run to path, in the format of
+// its extension.
+func writeSummary(path string, result *suite.Result) error {
+	format := outputJSON
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		format = outputYAML
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create summary: %w", err)
+	}
+
+	if err := printDocument(f, format, result.Summary()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	return f.Close()
+}
+
 // projectid is a helper function that will generate a random project ID if one is not provided.
 func projectID(id string) string {
 	if id != "" {
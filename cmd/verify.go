@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tempestdx/cli/internal/config"
+	"github.com/tempestdx/cli/internal/runner"
+	"github.com/tempestdx/cli/internal/verify"
+)
+
+var (
+	verifyRoundTrip bool
+	verifyInputs    []string
+	verifyFailOn    string
+	verifyOutput    string
+
+	verifyCmd = &cobra.Command{
+		Use:   "verify <app-id>:<app-version>",
+		Short: "Check that an app follows the contract Tempest relies on.",
+		Long: `The verify command starts the app, and checks the contract Tempest relies on:
+
+- every operation declared as supported has a handler,
+- schemas are valid draft 2020-12 JSON schemas, with the Tempest $schema URLs,
+- resource types are unique, and links have a valid type and URL,
+- instructions are well-formed markdown.
+
+Handlers are probed with requests the app rejects before calling them. Use
+--round-trip to also create a resource of each type, then read, list and
+delete it, and check that the app reports the same resource throughout.
+
+Each finding has a severity: info, warning or error. The command fails when a
+finding is at least as severe as --fail-on, so it can run in CI before
+connecting the app.`,
+		Args:          cobra.ExactArgs(1),
+		RunE:          verifyRunE,
+		SilenceErrors: true,
+	}
+)
+
+func init() {
+	appCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().BoolVar(&verifyRoundTrip, "round-trip", false, "Create a resource of each type supporting create, then read, list and delete it.")
+	verifyCmd.Flags().StringArrayVar(&verifyInputs, "input", nil, "The input to create a resource of a type with during the round trip. Format: TYPE=JSON, TYPE=@file.json or TYPE=@file.yaml. Without an input, one is generated from the create input schema.")
+	verifyCmd.Flags().StringVar(&verifyFailOn, "fail-on", "error", "The lowest severity of findings that fails the command. Accepted values: 'info', 'warning', 'error'.")
+	verifyCmd.Flags().StringVar(&verifyOutput, "output", "", "Print the findings as a document instead of text. Accepted values: 'json', 'yaml'.")
+	addRequestFlags(verifyCmd.Flags())
+}
+
+func verifyRunE(cmd *cobra.Command, args []string) error {
+	id, version, err := splitAppVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	failOn, err := verify.ParseSeverity(verifyFailOn)
+	if err != nil {
+		return fmt.Errorf("--fail-on: %w", err)
+	}
+
+	switch verifyOutput {
+	case "", outputJSON, outputYAML:
+	default:
+		return fmt.Errorf("invalid --output %q. Accepted values: %s, %s", verifyOutput, outputJSON, outputYAML)
+	}
+
+	inputs := make(map[string]map[string]any)
+	for _, in := range verifyInputs {
+		typ, value, ok := strings.Cut(in, "=")
+		if !ok || typ == "" {
+			return fmt.Errorf("invalid --input %q. Format: TYPE=JSON, TYPE=@file.json or TYPE=@file.yaml", in)
+		}
+
+		inputs[typ], err = readInput(cmd.InOrStdin(), value)
+		if err != nil {
+			return fmt.Errorf("invalid --input for %s: %w", typ, err)
+		}
+	}
+
+	ev, err := testEnvironment()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := testMetadata()
+	if err != nil {
+		return err
+	}
+
+	cfg, cfgDir, err := config.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	appVersion := cfg.LookupAppByVersion(id, version)
+	if appVersion == nil {
+		return fmt.Errorf("app version %s:%s not found in config", id, version)
+	}
+
+	if !appPreserveBuildDir {
+		err := generateBuildDir(cfg, cfgDir, id, version)
+		if err != nil {
+			return fmt.Errorf("generate build dir: %w", err)
+		}
+	}
+
+	runner, cancel, err := runner.StartApp(context.Background(), cfg, cfgDir, id, appVersion, outputRunnerOptions(cmd, verifyOutput)...)
+	if err != nil {
+		return fmt.Errorf("start app: %w", err)
+	}
+	defer cancel()
+
+	findings, err := verify.Run(context.TODO(), runner.Client, verify.Options{
+		Metadata:  metadata,
+		Env:       ev,
+		RoundTrip: verifyRoundTrip,
+		Inputs:    inputs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if verifyOutput != "" {
+		// Always print a list, even without findings.
+		if findings == nil {
+			findings = []verify.Finding{}
+		}
+		if err := printDocument(cmd.OutOrStdout(), verifyOutput, findings); err != nil {
+			return err
+		}
+	} else {
+		printFindings(cmd, findings)
+	}
+
+	var failed int
+	for _, f := range findings {
+		if f.Severity >= failOn {
+			failed++
+		}
+	}
+	if failed > 0 {
+		// The findings explain the failure, the usage would only add noise.
+		cmd.SilenceUsage = true
+		return fmt.Errorf("verification failed: %s with severity %s or higher", plural(failed, "finding"), failOn)
+	}
+
+	return nil
+}
+
+// printFindings prints the findings grouped by resource type, and the number
+// of findings of each severity.
+func printFindings(cmd *cobra.Command, findings []verify.Finding) {
+	var types []string
+	byType := make(map[string][]verify.Finding)
+	for _, f := range findings {
+		if _, ok := byType[f.Type]; !ok {
+			types = append(types, f.Type)
+		}
+		byType[f.Type] = append(byType[f.Type], f)
+	}
+
+	counts := make(map[verify.Severity]int)
+	for _, t := range types {
+		if t == "" {
+			cmd.Println("\napp")
+		} else {
+			cmd.Printf("\n%s\n", t)
+		}
+
+		for _, f := range byType[t] {
+			counts[f.Severity]++
+
+			var icon string
+			switch f.Severity {
+			case verify.SeverityError:
+				icon = "❌"
+			case verify.SeverityWarning:
+				icon = "⚠️ "
+			default:
+				icon = "ℹ️ "
+			}
+			cmd.Printf("  %s %s: %s\n", icon, f.Check, f.Message)
+		}
+	}
+
+	if len(findings) == 0 {
+		cmd.Println("✅ No findings.")
+		return
+	}
+
+	cmd.Printf("\n%s, %s, %d info\n",
+		plural(counts[verify.SeverityError], "error"),
+		plural(counts[verify.SeverityWarning], "warning"),
+		counts[verify.SeverityInfo],
+	)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
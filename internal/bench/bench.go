@@ -0,0 +1,225 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+)
+
+// CodeOK is the code of successful requests in Result.Codes.
+const CodeOK = "ok"
+
+// Options configure a benchmark.
+type Options struct {
+	// Concurrency is the number of requests in flight at most.
+	Concurrency int
+	// Rate is the number of requests started per second at most. Zero means
+	// no limit: each worker starts a request as soon as the previous one is
+	// done.
+	Rate float64
+	// Duration is how long requests are started for. Requests in flight at
+	// the end are awaited.
+	Duration time.Duration
+	// Requests stops the benchmark after that many requests, when positive.
+	Requests int
+	// Timeout of each request.
+	Timeout time.Duration
+	// Do sends the request number seq, from 1, for the worker numbered from
+	// 0.
+	Do func(ctx context.Context, worker, seq int) error
+}
+
+// Result of a benchmark.
+type Result struct {
+	Requests  int `json:"requests" yaml:"requests"`
+	Succeeded int `json:"succeeded" yaml:"succeeded"`
+	Failed    int `json:"failed" yaml:"failed"`
+	// DurationMS is the time from the first request to the end of the last
+	// one.
+	DurationMS float64 `json:"duration_ms" yaml:"duration_ms"`
+	// Throughput is the number of requests completed per second.
+	Throughput float64 `json:"throughput" yaml:"throughput"`
+	// Latency of the requests, successful or not.
+	Latency Latency `json:"latency" yaml:"latency"`
+	// Codes counts the requests by Connect code, "ok" for successful ones.
+	// Errors which are not Connect errors are counted as "unknown".
+	Codes map[string]int `json:"codes" yaml:"codes"`
+	// Errors holds an example message for each code of failed requests.
+	Errors map[string]string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Latency distribution, in milliseconds.
+type Latency struct {
+	Min  float64 `json:"min" yaml:"min"`
+	Mean float64 `json:"mean" yaml:"mean"`
+	P50  float64 `json:"p50" yaml:"p50"`
+	P90  float64 `json:"p90" yaml:"p90"`
+	P99  float64 `json:"p99" yaml:"p99"`
+	Max  float64 `json:"max" yaml:"max"`
+}
+
+type sample struct {
+	latency time.Duration
+	code    string
+	err     error
+}
+
+// Run sends requests with opts.Do from opts.Concurrency workers, until
+// opts.Duration elapsed, opts.Requests were sent, or ctx is done.
+func Run(ctx context.Context, opts Options) *Result {
+	concurrency := max(opts.Concurrency, 1)
+
+	runCtx := ctx
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	// Each token lets a worker start a request. Without a rate, tokens are
+	// handed out as fast as workers take them.
+	tokens := make(chan int)
+	go func() {
+		defer close(tokens)
+
+		var tick <-chan time.Time
+		if opts.Rate > 0 {
+			// Rates above one request per nanosecond are no limit.
+			t := time.NewTicker(max(time.Duration(float64(time.Second)/opts.Rate), 1))
+			defer t.Stop()
+			tick = t.C
+		}
+
+		for seq := 1; opts.Requests <= 0 || seq <= opts.Requests; seq++ {
+			if tick != nil && seq > 1 {
+				select {
+				case <-tick:
+				case <-runCtx.Done():
+					return
+				}
+			}
+
+			select {
+			case tokens <- seq:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var samples []sample
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for worker := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for seq := range tokens {
+				// Requests in flight at the end of the run are not
+				// canceled, only ctx and their timeout cancel them.
+				reqCtx, cancel := ctx, context.CancelFunc(func() {})
+				if opts.Timeout > 0 {
+					reqCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+				}
+
+				begin := time.Now()
+				err := opts.Do(reqCtx, worker, seq)
+				s := sample{latency: time.Since(begin), code: CodeOK, err: err}
+				cancel()
+
+				if err != nil {
+					s.code = code(err)
+				}
+
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return summarize(samples, time.Since(start))
+}
+
+func code(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.CodeDeadlineExceeded.String()
+	case errors.Is(err, context.Canceled):
+		return connect.CodeCanceled.String()
+	}
+
+	var ce *connect.Error
+	if errors.As(err, &ce) {
+		return ce.Code().String()
+	}
+
+	return connect.CodeUnknown.String()
+}
+
+func summarize(samples []sample, elapsed time.Duration) *Result {
+	r := &Result{
+		Requests:   len(samples),
+		DurationMS: milliseconds(elapsed),
+		Codes:      make(map[string]int),
+	}
+	if elapsed > 0 {
+		r.Throughput = math.Round(float64(len(samples))/elapsed.Seconds()*100) / 100
+	}
+
+	latencies := make([]time.Duration, 0, len(samples))
+	var total time.Duration
+	for _, s := range samples {
+		latencies = append(latencies, s.latency)
+		total += s.latency
+		r.Codes[s.code]++
+
+		if s.err == nil {
+			r.Succeeded++
+			continue
+		}
+
+		r.Failed++
+		if r.Errors == nil {
+			r.Errors = make(map[string]string)
+		}
+		if _, ok := r.Errors[s.code]; !ok {
+			r.Errors[s.code] = s.err.Error()
+		}
+	}
+
+	if len(latencies) == 0 {
+		return r
+	}
+
+	slices.Sort(latencies)
+	r.Latency = Latency{
+		Min:  milliseconds(latencies[0]),
+		Mean: milliseconds(total / time.Duration(len(latencies))),
+		P50:  milliseconds(percentile(latencies, 50)),
+		P90:  milliseconds(percentile(latencies, 90)),
+		P99:  milliseconds(percentile(latencies, 99)),
+		Max:  milliseconds(latencies[len(latencies)-1]),
+	}
+
+	return r
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
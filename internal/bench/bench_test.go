@@ -0,0 +1,124 @@
+package bench_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/bench"
+)
+
+func TestRun(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	result := bench.Run(context.Background(), bench.Options{
+		Concurrency: 4,
+		Requests:    100,
+		Do: func(ctx context.Context, worker, seq int) error {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				m := maxInFlight.Load()
+				if n <= m || maxInFlight.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+
+			switch {
+			case seq%10 == 0:
+				return connect.NewError(connect.CodeUnavailable, errors.New("overloaded"))
+			case seq == 7:
+				return errors.New("boom")
+			}
+			return nil
+		},
+	})
+
+	assert.Equal(t, 100, result.Requests)
+	assert.Equal(t, 89, result.Succeeded)
+	assert.Equal(t, 11, result.Failed)
+	assert.Equal(t, map[string]int{bench.CodeOK: 89, "unavailable": 10, "unknown": 1}, result.Codes)
+	assert.Equal(t, map[string]string{"unavailable": "unavailable: overloaded", "unknown": "boom"}, result.Errors)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(4))
+
+	l := result.Latency
+	assert.GreaterOrEqual(t, l.Min, 1.0)
+	assert.True(t, l.Min <= l.P50 && l.P50 <= l.P90 && l.P90 <= l.P99 && l.P99 <= l.Max, "%+v", l)
+	assert.Positive(t, result.Throughput)
+}
+
+func TestRunRateAndDuration(t *testing.T) {
+	result := bench.Run(context.Background(), bench.Options{
+		Concurrency: 8,
+		Rate:        100,
+		Duration:    300 * time.Millisecond,
+		Do: func(ctx context.Context, worker, seq int) error {
+			return nil
+		},
+	})
+
+	// 100 requests per second for 300ms.
+	assert.InDelta(t, 30, result.Requests, 10)
+}
+
+func TestRunHugeRate(t *testing.T) {
+	result := bench.Run(context.Background(), bench.Options{
+		Rate:     1e12,
+		Requests: 3,
+		Do: func(ctx context.Context, worker, seq int) error {
+			return nil
+		},
+	})
+
+	assert.Equal(t, 3, result.Requests)
+}
+
+func TestRunTimeout(t *testing.T) {
+	result := bench.Run(context.Background(), bench.Options{
+		Requests: 2,
+		Timeout:  10 * time.Millisecond,
+		Do: func(ctx context.Context, worker, seq int) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	assert.Equal(t, map[string]int{"deadline_exceeded": 2}, result.Codes)
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := bench.NewTemplate(map[string]any{
+		"name": "item-{{.Seq}}",
+		"size": float64(3),
+		"tags": []any{"worker-{{.Worker}}", "fixed"},
+		"id":   "{{uuid}}",
+		"n":    "{{rand 1}}",
+	})
+	require.NoError(t, err)
+
+	input, err := tmpl.Execute(bench.TemplateData{Seq: 12, Worker: 3})
+	require.NoError(t, err)
+
+	assert.Equal(t, "item-12", input["name"])
+	assert.Equal(t, float64(3), input["size"])
+	assert.Equal(t, []any{"worker-3", "fixed"}, input["tags"])
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, input["id"])
+	assert.Equal(t, "0", input["n"])
+
+	id, err := bench.ExecuteString("item-{{.Seq}}", bench.TemplateData{Seq: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "item-5", id)
+
+	_, err = bench.NewTemplate(map[string]any{"name": "{{.Seq"})
+	assert.ErrorContains(t, err, "invalid template")
+
+	tmpl, err = bench.NewTemplate(map[string]any{"name": "{{.Missing}}"})
+	require.NoError(t, err)
+	_, err = tmpl.Execute(bench.TemplateData{})
+	assert.ErrorContains(t, err, "can't evaluate field Missing")
+}
@@ -0,0 +1,145 @@
+package bench
+
+import (
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data input templates are executed with.
+type TemplateData struct {
+	// Seq is the number of the request, from 1.
+	Seq int
+	// Worker is the number of the worker sending the request, from 0.
+	Worker int
+}
+
+var funcs = template.FuncMap{
+	// rand returns a random integer in [0, n).
+	"rand": func(n int) int {
+		return rand.IntN(max(n, 1))
+	},
+	"uuid": func() string {
+		b := make([]byte, 16)
+		_, _ = cryptorand.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	},
+}
+
+// Template generates the input of each request from a template input, whose
+// strings may contain text/template actions, such as "item-{{.Seq}}" or
+// "{{uuid}}". See TemplateData for the fields, and the rand and uuid
+// functions.
+type Template struct {
+	value any
+}
+
+// NewTemplate parses the templates in the strings of input.
+func NewTemplate(input map[string]any) (*Template, error) {
+	v, err := parse(input, "$")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{value: v}, nil
+}
+
+// Execute returns the input of a request.
+func (t *Template) Execute(data TemplateData) (map[string]any, error) {
+	v, err := execute(t.value, data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _ := v.(map[string]any)
+	return m, nil
+}
+
+// ExecuteString executes the template actions of s, such as an external ID.
+func ExecuteString(s string, data TemplateData) (string, error) {
+	v, err := parse(s, "")
+	if err != nil {
+		return "", err
+	}
+
+	out, err := execute(v, data)
+	if err != nil {
+		return "", err
+	}
+
+	return out.(string), nil
+}
+
+// parse replaces the strings of v containing actions by their template.
+func parse(v any, path string) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		t, err := template.New(path).Funcs(funcs).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return t, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			p, err := parse(e, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = p
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(v))
+		for i, e := range v {
+			p, err := parse(e, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func execute(v any, data TemplateData) (any, error) {
+	switch v := v.(type) {
+	case *template.Template:
+		var b strings.Builder
+		if err := v.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		return b.String(), nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			x, err := execute(e, data)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = x
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, e := range v {
+			x, err := execute(e, data)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, x)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
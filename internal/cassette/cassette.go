@@ -0,0 +1,178 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the values of sensitive headers, query parameters and body
+// fields in cassettes.
+const Redacted = "REDACTED"
+
+// Headers whose values are never written to cassettes, since cassettes are
+// meant to be committed.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Amz-Security-Token",
+}
+
+// Headers which only apply to a single connection, and are not forwarded.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Cassette is a recording of the HTTP interactions of an app.
+type Cassette struct {
+	Interactions []*Interaction `yaml:"interactions"`
+}
+
+// Interaction is a request of the app, and the response it got.
+type Interaction struct {
+	Request  Request  `yaml:"request"`
+	Response Response `yaml:"response"`
+}
+
+type Request struct {
+	Method  string      `yaml:"method"`
+	URL     string      `yaml:"url"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    Body        `yaml:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int         `yaml:"status"`
+	Headers http.Header `yaml:"headers,omitempty"`
+	Body    Body        `yaml:"body,omitempty"`
+}
+
+// Body is written as a string in cassettes, or as base64 when it is not valid
+// UTF-8.
+type Body []byte
+
+func (b Body) MarshalYAML() (any, error) {
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!binary",
+		Value: base64.StdEncoding.EncodeToString(b),
+	}, nil
+}
+
+func (b *Body) UnmarshalYAML(n *yaml.Node) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+
+	// yaml.v3 decodes !!binary scalars to their bytes.
+	*b = Body(s)
+	return nil
+}
+
+// Load reads the cassette at path.
+func Load(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Cassette
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating its directory when needed.
+func (c *Cassette) Save(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// recordedHeaders returns the headers to write to a cassette: sensitive ones
+// are redacted, and the ones of the connection left out.
+func recordedHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range hopHeaders {
+		out.Del(k)
+	}
+	out.Del("Content-Length")
+
+	for _, k := range sensitiveHeaders {
+		if _, ok := out[k]; ok {
+			out[k] = []string{Redacted}
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// find returns the first interaction matching the method and URL which was
+// not replayed yet, preferring the ones with the same body. Once they were all
+// replayed, the last one matches again, for apps polling an URL.
+func (c *Cassette) find(method, url string, body []byte, replayed []bool) int {
+	match := func(i int, sameBody bool) bool {
+		req := c.Interactions[i].Request
+		return req.Method == method && req.URL == url && (!sameBody || bytes.Equal(req.Body, body))
+	}
+
+	for _, sameBody := range []bool{true, false} {
+		for i := range c.Interactions {
+			if !replayed[i] && match(i, sameBody) {
+				return i
+			}
+		}
+	}
+
+	last := -1
+	for i := range c.Interactions {
+		if match(i, false) {
+			last = i
+		}
+	}
+
+	return last
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
@@ -0,0 +1,233 @@
+package cassette_test
+
+import (
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/cassette"
+)
+
+// client returns a client sending its requests through the proxy, and
+// trusting its certificate authority.
+func client(t *testing.T, p *cassette.Proxy) *http.Client {
+	t.Helper()
+
+	ca, err := os.ReadFile(p.CAFile())
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+
+	proxyURL, err := url.Parse(p.URL())
+	require.NoError(t, err)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	transport.TLSClientConfig.RootCAs = pool
+
+	return &http.Client{Transport: transport}
+}
+
+func send(t *testing.T, c *http.Client, method, url, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(b)
+}
+
+func TestRecordReplay(t *testing.T) {
+	count := 0
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		b, _ := io.ReadAll(r.Body)
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, "created "+string(b))
+		default:
+			_, _ = io.WriteString(w, r.URL.Path)
+		}
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "create.yaml")
+
+	p, err := cassette.Start(cassette.Options{
+		Mode:      cassette.ModeRecord,
+		Path:      path,
+		Transport: upstream.Client().Transport,
+	})
+	require.NoError(t, err)
+
+	c := client(t, p)
+	status, body := send(t, c, http.MethodPost, upstream.URL+"/things", "a")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created a", body)
+	status, body = send(t, c, http.MethodPost, upstream.URL+"/things", "b")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created b", body)
+	status, body = send(t, c, http.MethodGet, upstream.URL+"/things/1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "/things/1", body)
+
+	assert.Equal(t, 3, p.Interactions())
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close())
+	assert.Equal(t, 3, count)
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), cassette.Redacted)
+	assert.NotContains(t, string(raw), "secret")
+
+	upstream.Close()
+
+	p, err = cassette.Start(cassette.Options{Mode: cassette.ModeReplay, Path: path})
+	require.NoError(t, err)
+	defer p.Close()
+
+	c = client(t, p)
+	// Requests with the same method and URL are told apart by their body.
+	status, body = send(t, c, http.MethodPost, upstream.URL+"/things", "b")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created b", body)
+	status, body = send(t, c, http.MethodPost, upstream.URL+"/things", "a")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created a", body)
+
+	// Replayed interactions are reused once they were all replayed.
+	for range 2 {
+		status, body = send(t, c, http.MethodGet, upstream.URL+"/things/1", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "/things/1", body)
+	}
+
+	status, _ = send(t, c, http.MethodDelete, upstream.URL+"/things/1", "")
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, []string{"DELETE " + upstream.URL + "/things/1"}, p.Misses())
+	assert.Equal(t, 3, count)
+}
+
+func TestPlainHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write([]byte{0xff, 0x00, 0xfe})
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "plain.yaml")
+
+	p, err := cassette.Start(cassette.Options{Mode: cassette.ModeRecord, Path: path})
+	require.NoError(t, err)
+
+	_, body := send(t, client(t, p), http.MethodGet, upstream.URL+"/bin", "")
+	assert.Equal(t, "\xff\x00\xfe", body)
+	require.NoError(t, p.Close())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "!!binary")
+	assert.NotContains(t, string(raw), "session=secret")
+
+	p, err = cassette.Start(cassette.Options{Mode: cassette.ModeReplay, Path: path})
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, body = send(t, client(t, p), http.MethodGet, upstream.URL+"/bin", "")
+	assert.Equal(t, "\xff\x00\xfe", body)
+}
+
+func TestRedaction(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"tok-123","expires_in":3600,"session":{"Session_Key":"sess-9"}}`)
+	}))
+	defer upstream.Close()
+
+	path := filepath.Join(t.TempDir(), "oauth.yaml")
+	tokenURL := upstream.URL + "/oauth/token?page=2&sig=sig-456"
+	form := "grant_type=client_credentials&client_secret=cs-789"
+
+	post := func(c *http.Client) string {
+		t.Helper()
+
+		resp, err := c.Post(tokenURL, "application/x-www-form-urlencoded", strings.NewReader(form))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	opts := cassette.Options{Path: path, Redact: cassette.Redaction{BodyFields: []string{"session_key"}}}
+
+	opts.Mode = cassette.ModeRecord
+	p, err := cassette.Start(opts)
+	require.NoError(t, err)
+
+	// The app gets the response as it was sent.
+	assert.Contains(t, post(client(t, p)), "tok-123")
+	require.NoError(t, p.Close())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{"tok-123", "sig-456", "cs-789", "sess-9"} {
+		assert.NotContains(t, string(raw), secret)
+	}
+	assert.Contains(t, string(raw), "page=2")
+	assert.Contains(t, string(raw), `"expires_in":3600`)
+
+	// Requests are redacted alike to match the cassette.
+	opts.Mode = cassette.ModeReplay
+	p, err = cassette.Start(opts)
+	require.NoError(t, err)
+	defer p.Close()
+
+	assert.JSONEq(t, `{"access_token":"REDACTED","expires_in":3600,"session":{"Session_Key":"REDACTED"}}`, post(client(t, p)))
+	assert.Empty(t, p.Misses())
+}
+
+func TestCloseSaveError(t *testing.T) {
+	// The cassette cannot be written below a file.
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	p, err := cassette.Start(cassette.Options{Mode: cassette.ModeRecord, Path: filepath.Join(file, "c.yaml")})
+	require.NoError(t, err)
+
+	err = p.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "save cassette")
+	// Callers closing the proxy again, once it was closed on stop, get the
+	// error too.
+	assert.Equal(t, err, p.Close())
+}
+
+func TestStartMissingCassette(t *testing.T) {
+	_, err := cassette.Start(cassette.Options{
+		Mode: cassette.ModeReplay,
+		Path: filepath.Join(t.TempDir(), "missing.yaml"),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "load cassette")
+}
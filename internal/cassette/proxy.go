@@ -0,0 +1,387 @@
+package cassette
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Mode is whether a proxy records or replays interactions.
+type Mode int
+
+const (
+	// ModeRecord forwards the requests of the app to their host, and records
+	// the interactions to the cassette.
+	ModeRecord Mode = iota
+	// ModeReplay answers the requests of the app with the interactions of the
+	// cassette, without any connection to their host.
+	ModeReplay
+)
+
+// Options configure a proxy.
+type Options struct {
+	Mode Mode
+	// Path of the cassette, written by Close when recording.
+	Path string
+	// Transport sends the requests to their host when recording. It defaults
+	// to a clone of http.DefaultTransport.
+	Transport http.RoundTripper
+	// Redact lists values to leave out of the cassette, besides the ones of
+	// DefaultRedaction. The requests are redacted alike to be matched when
+	// replaying.
+	Redact Redaction
+}
+
+// Proxy is an HTTP proxy recording or replaying the interactions of an app.
+// HTTPS requests are intercepted with certificates issued by a certificate
+// authority generated for the proxy, which the app must trust.
+type Proxy struct {
+	opts      Options
+	redact    Redaction
+	ca        *authority
+	dir       string
+	listener  net.Listener
+	server    *http.Server
+	tunnels   *connListener
+	tunnelSrv *http.Server
+
+	mu       sync.Mutex
+	cassette *Cassette
+	replayed []bool
+	misses   []string
+	closed   bool
+	closeErr error
+}
+
+// Start loads the cassette when replaying, and starts a proxy on a local
+// port.
+func Start(opts Options) (*Proxy, error) {
+	c := &Cassette{}
+	if opts.Mode == ModeReplay {
+		var err error
+		c, err = Load(opts.Path)
+		if err != nil {
+			return nil, fmt.Errorf("load cassette: %w", err)
+		}
+	}
+
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	ca, err := newAuthority()
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate authority: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "tempest-proxy-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), ca.PEM, 0o600); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	p := &Proxy{
+		opts:     opts,
+		redact:   DefaultRedaction.merge(opts.Redact),
+		ca:       ca,
+		dir:      dir,
+		listener: l,
+		tunnels:  newConnListener(l.Addr()),
+		cassette: c,
+		replayed: make([]bool, len(c.Interactions)),
+	}
+	p.server = &http.Server{Handler: p}
+	// The requests of CONNECT tunnels are served once TLS is terminated.
+	p.tunnelSrv = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Scheme = "https"
+		r.URL.Host = r.Host
+		p.handle(w, r)
+	})}
+
+	go func() { _ = p.server.Serve(l) }()
+	go func() { _ = p.tunnelSrv.Serve(p.tunnels) }()
+
+	return p, nil
+}
+
+// URL of the proxy, such as http://127.0.0.1:41234.
+func (p *Proxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// CAFile is the path of the PEM encoded certificate of the authority the app
+// must trust.
+func (p *Proxy) CAFile() string {
+	return filepath.Join(p.dir, "ca.pem")
+}
+
+// Env returns the environment variables making an app send its requests
+// through the proxy, and trust its certificate authority.
+func (p *Proxy) Env() []string {
+	return []string{
+		"HTTP_PROXY=" + p.URL(),
+		"HTTPS_PROXY=" + p.URL(),
+		"http_proxy=" + p.URL(),
+		"https_proxy=" + p.URL(),
+		"NO_PROXY=",
+		"no_proxy=",
+		"SSL_CERT_FILE=" + p.CAFile(),
+		// SSL_CERT_FILE is ignored on macOS, the app trusts this one in
+		// http.DefaultTransport.
+		"TEMPEST_PROXY_CA_FILE=" + p.CAFile(),
+	}
+}
+
+// Interactions returns the number of interactions recorded so far, or loaded
+// from the cassette when replaying.
+func (p *Proxy) Interactions() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.cassette.Interactions)
+}
+
+// Misses returns the requests, as "METHOD URL", which had no interaction in
+// the cassette when replaying.
+func (p *Proxy) Misses() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.misses...)
+}
+
+// Close stops the proxy and writes the cassette when recording. Calling it
+// again returns the error of the first call.
+func (p *Proxy) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return p.closeErr
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	err := errors.Join(p.server.Close(), p.tunnelSrv.Close())
+	if p.opts.Mode == ModeRecord {
+		p.mu.Lock()
+		if saveErr := p.cassette.Save(p.opts.Path); saveErr != nil {
+			err = errors.Join(err, fmt.Errorf("save cassette: %w", saveErr))
+		}
+		p.mu.Unlock()
+	}
+
+	err = errors.Join(err, os.RemoveAll(p.dir))
+
+	p.mu.Lock()
+	p.closeErr = err
+	p.mu.Unlock()
+
+	return err
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.connect(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "cassette: not a proxy request", http.StatusBadRequest)
+		return
+	}
+
+	p.handle(w, r)
+}
+
+// connect terminates the TLS of a tunnel with a certificate for its host, and
+// hands the connection over to the tunnel server.
+func (p *Proxy) connect(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "cassette: tunnels are not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" {
+				return p.ca.certificate(hello.ServerName)
+			}
+			return p.ca.certificate(host)
+		},
+	})
+
+	if !p.tunnels.push(tlsConn) {
+		_ = conn.Close()
+	}
+}
+
+func (p *Proxy) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cassette: read request: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	req := Request{
+		Method:  r.Method,
+		URL:     p.redact.url(r.URL),
+		Headers: recordedHeaders(r.Header),
+		Body:    p.redact.body(body, r.Header.Get("Content-Type")),
+	}
+
+	var res *Response
+	if p.opts.Mode == ModeReplay {
+		res = p.replay(req)
+	} else {
+		res, err = p.forward(r, body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("cassette: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		recorded := *res
+		recorded.Headers = recordedHeaders(res.Headers)
+		recorded.Body = p.redact.body(res.Body, res.Headers.Get("Content-Type"))
+
+		p.mu.Lock()
+		p.cassette.Interactions = append(p.cassette.Interactions, &Interaction{Request: req, Response: recorded})
+		p.mu.Unlock()
+	}
+
+	for k, v := range res.Headers {
+		if k == "Content-Length" {
+			continue
+		}
+		w.Header()[k] = v
+	}
+	removeHopHeaders(w.Header())
+	w.Header().Set("Content-Length", fmt.Sprint(len(res.Body)))
+	w.WriteHeader(res.Status)
+	_, _ = w.Write(res.Body)
+}
+
+// forward sends the request to its host.
+func (p *Proxy) forward(r *http.Request, body []byte) (*Response, error) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	removeHopHeaders(out.Header)
+	// The transport asks for compressed responses itself, and decompresses
+	// them, so that cassettes hold readable bodies.
+	out.Header.Del("Accept-Encoding")
+
+	resp, err := p.opts.Transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	return &Response{
+		Status:  resp.StatusCode,
+		Headers: resp.Header,
+		Body:    b,
+	}, nil
+}
+
+// replay returns the response recorded for the request, or a Bad Gateway
+// response when there is none.
+func (p *Proxy) replay(req Request) *Response {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := p.cassette.find(req.Method, req.URL, req.Body, p.replayed)
+	if i < 0 {
+		p.misses = append(p.misses, req.Method+" "+req.URL)
+		return &Response{
+			Status:  http.StatusBadGateway,
+			Headers: http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+			Body:    []byte(fmt.Sprintf("cassette: no recorded interaction for %s %s\n", req.Method, req.URL)),
+		}
+	}
+	p.replayed[i] = true
+
+	return &p.cassette.Interactions[i].Response
+}
+
+// connListener is a net.Listener accepting the connections pushed to it.
+type connListener struct {
+	addr   net.Addr
+	conns  chan net.Conn
+	done   chan struct{}
+	closer sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// push hands over conn to the listener, and returns false once it is closed.
+func (l *connListener) push(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closer.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,145 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Redaction lists the values left out of cassettes besides the ones of the
+// sensitive headers. Names are matched case insensitively.
+type Redaction struct {
+	// QueryParams are the query parameters of the URLs whose values are
+	// redacted, such as signatures of presigned URLs.
+	QueryParams []string
+	// BodyFields are the fields whose values are redacted in the JSON and
+	// form bodies of requests and responses, at any depth.
+	BodyFields []string
+}
+
+// DefaultRedaction is always applied, on top of the Redaction of the Options.
+var DefaultRedaction = Redaction{
+	QueryParams: []string{
+		"access_token",
+		"api_key",
+		"apikey",
+		"client_secret",
+		"key",
+		"password",
+		"sig",
+		"signature",
+		"token",
+		"X-Amz-Credential",
+		"X-Amz-Security-Token",
+		"X-Amz-Signature",
+	},
+	BodyFields: []string{
+		"access_token",
+		"client_secret",
+		"id_token",
+		"password",
+		"refresh_token",
+	},
+}
+
+// merge returns the names of both redactions.
+func (r Redaction) merge(o Redaction) Redaction {
+	return Redaction{
+		QueryParams: append(slices.Clone(r.QueryParams), o.QueryParams...),
+		BodyFields:  append(slices.Clone(r.BodyFields), o.BodyFields...),
+	}
+}
+
+func contains(names []string, name string) bool {
+	return slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) })
+}
+
+// url returns the URL with the values of the redacted query parameters
+// replaced. URLs without them are returned unchanged.
+func (r Redaction) url(u *url.URL) string {
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil || !redactValues(q, r.QueryParams) {
+		return u.String()
+	}
+
+	out := *u
+	out.RawQuery = q.Encode()
+	return out.String()
+}
+
+// body returns the body with the values of the redacted fields replaced, when
+// it is a JSON document or a form. Other bodies are returned unchanged.
+func (r Redaction) body(b []byte, contentType string) []byte {
+	if len(b) == 0 {
+		return b
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(b))
+		if err != nil || !redactValues(form, r.BodyFields) {
+			return b
+		}
+		return []byte(form.Encode())
+	}
+
+	if !json.Valid(b) {
+		return b
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var doc any
+	if err := d.Decode(&doc); err != nil || !r.redactJSON(doc) {
+		return b
+	}
+
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(doc); err != nil {
+		return b
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// redactJSON replaces the values of the redacted fields of v, and returns
+// whether there were any.
+func (r Redaction) redactJSON(v any) bool {
+	redacted := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if contains(r.BodyFields, k) {
+				v[k] = Redacted
+				redacted = true
+				continue
+			}
+			redacted = r.redactJSON(child) || redacted
+		}
+	case []any:
+		for _, child := range v {
+			redacted = r.redactJSON(child) || redacted
+		}
+	}
+
+	return redacted
+}
+
+// redactValues replaces the values of the names in values, and returns
+// whether there were any.
+func redactValues(values url.Values, names []string) bool {
+	redacted := false
+	for k, v := range values {
+		if contains(names, k) {
+			values[k] = slices.Repeat([]string{Redacted}, len(v))
+			redacted = true
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,175 @@
+// Package cover turns the coverage counters written by an instrumented app into
+// a coverage profile and a summary.
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Summary is the statement coverage of the packages of a profile.
+type Summary struct {
+	Packages   []*Package `json:"packages" yaml:"packages"`
+	Statements int        `json:"statements" yaml:"statements"`
+	Covered    int        `json:"covered" yaml:"covered"`
+	Percent    float64    `json:"percent" yaml:"percent"`
+}
+
+// Package is the statement coverage of a package.
+type Package struct {
+	Path       string  `json:"path" yaml:"path"`
+	Statements int     `json:"statements" yaml:"statements"`
+	Covered    int     `json:"covered" yaml:"covered"`
+	Percent    float64 `json:"percent" yaml:"percent"`
+}
+
+// Profile merges the counters in dir into a coverage profile, in the text
+// format of go test -coverprofile.
+func Profile(dir string) ([]byte, error) {
+	out, err := os.CreateTemp("", "tempest-cover-*.out")
+	if err != nil {
+		return nil, err
+	}
+	_ = out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+dir, "-o="+out.Name())
+	if b, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("go tool covdata: %w\n%s", err, b)
+	}
+
+	return os.ReadFile(out.Name())
+}
+
+// Summarize returns the coverage of the packages of a profile.
+func Summarize(profile []byte) (*Summary, error) {
+	type block struct {
+		statements int
+		covered    bool
+	}
+
+	// Blocks may be listed several times, by the counters of several runs.
+	blocks := make(map[string]*block)
+	var order []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || (n == 1 && strings.HasPrefix(line, "mode:")) {
+			continue
+		}
+
+		// name.go:line.column,line.column statements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 || !strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("invalid profile line %d: %q", n, line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile line %d: %q", n, line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile line %d: %q", n, line)
+		}
+
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+			order = append(order, fields[0])
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]*Package)
+	s := &Summary{}
+	for _, key := range order {
+		b := blocks[key]
+		file, _, _ := strings.Cut(key, ":")
+		dir := path.Dir(file)
+
+		p, ok := packages[dir]
+		if !ok {
+			p = &Package{Path: dir}
+			packages[dir] = p
+			s.Packages = append(s.Packages, p)
+		}
+
+		p.Statements += b.statements
+		s.Statements += b.statements
+		if b.covered {
+			p.Covered += b.statements
+			s.Covered += b.statements
+		}
+	}
+
+	slices.SortFunc(s.Packages, func(a, b *Package) int { return strings.Compare(a.Path, b.Path) })
+	for _, p := range s.Packages {
+		p.Percent = percent(p.Covered, p.Statements)
+	}
+	s.Percent = percent(s.Covered, s.Statements)
+
+	return s, nil
+}
+
+// Filter keeps the blocks of the files of a profile whose import path starts
+// with prefix, such as the apps among the packages of the build directory.
+func Filter(profile []byte, prefix string) []byte {
+	lines := strings.SplitAfter(string(profile), "\n")
+	kept := lines[:0]
+	for i, l := range lines {
+		if (i == 0 && strings.HasPrefix(l, "mode:")) || strings.HasPrefix(l, prefix) {
+			kept = append(kept, l)
+		}
+	}
+
+	return []byte(strings.Join(kept, ""))
+}
+
+// Rewrite replaces the import path prefix from of the files of a profile with
+// to, such as the path of the module of the apps instead of the one of the
+// build directory.
+func Rewrite(profile []byte, from, to string) []byte {
+	lines := strings.SplitAfter(string(profile), "\n")
+	for i, l := range lines {
+		if rest, ok := strings.CutPrefix(l, from+"/"); ok {
+			lines[i] = to + "/" + rest
+		}
+	}
+
+	return []byte(strings.Join(lines, ""))
+}
+
+// ModulePath returns the path of the module whose go.mod is in dir, or an
+// empty string when there is none.
+func ModulePath(dir string) string {
+	b, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+
+	return modfile.ModulePath(b)
+}
+
+func percent(covered, statements int) float64 {
+	if statements == 0 {
+		return 0
+	}
+
+	return math.Round(float64(covered)/float64(statements)*1000) / 10
+}
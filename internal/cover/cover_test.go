@@ -0,0 +1,72 @@
+package cover_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/cover"
+)
+
+const profile = `mode: set
+tempestappserver/apps/db/v1/app.go:10.2,12.3 3 1
+tempestappserver/apps/db/v1/app.go:14.2,15.3 2 0
+tempestappserver/apps/db/v1/app.go:14.2,15.3 2 1
+tempestappserver/apps/db/v1/app.go:20.2,21.3 1 0
+tempestappserver/apps/cache/v1/app.go:5.2,9.3 4 0
+`
+
+func TestSummarize(t *testing.T) {
+	s, err := cover.Summarize([]byte(profile))
+	require.NoError(t, err)
+
+	assert.Equal(t, &cover.Summary{
+		Packages: []*cover.Package{
+			{Path: "tempestappserver/apps/cache/v1", Statements: 4, Covered: 0, Percent: 0},
+			{Path: "tempestappserver/apps/db/v1", Statements: 6, Covered: 5, Percent: 83.3},
+		},
+		Statements: 10,
+		Covered:    5,
+		Percent:    50,
+	}, s)
+
+	s, err = cover.Summarize([]byte("mode: set\n"))
+	require.NoError(t, err)
+	assert.Empty(t, s.Packages)
+	assert.Zero(t, s.Percent)
+
+	_, err = cover.Summarize([]byte("mode: set\napp.go:1.1,2.2 x 1\n"))
+	require.Error(t, err)
+	assert.Equal(t, `invalid profile line 2: "app.go:1.1,2.2 x 1"`, err.Error())
+}
+
+func TestFilter(t *testing.T) {
+	got := cover.Filter([]byte("mode: set\ntempestappserver/main.go:1.1,2.2 1 1\n"+profile[len("mode: set\n"):]), "tempestappserver/apps/db/")
+	assert.Equal(t, `mode: set
+tempestappserver/apps/db/v1/app.go:10.2,12.3 3 1
+tempestappserver/apps/db/v1/app.go:14.2,15.3 2 0
+tempestappserver/apps/db/v1/app.go:14.2,15.3 2 1
+tempestappserver/apps/db/v1/app.go:20.2,21.3 1 0
+`, string(got))
+}
+
+func TestRewrite(t *testing.T) {
+	got := cover.Rewrite([]byte(profile), "tempestappserver", "example.com/myapps")
+	assert.Equal(t, `mode: set
+example.com/myapps/apps/db/v1/app.go:10.2,12.3 3 1
+example.com/myapps/apps/db/v1/app.go:14.2,15.3 2 0
+example.com/myapps/apps/db/v1/app.go:14.2,15.3 2 1
+example.com/myapps/apps/db/v1/app.go:20.2,21.3 1 0
+example.com/myapps/apps/cache/v1/app.go:5.2,9.3 4 0
+`, string(got))
+}
+
+func TestModulePath(t *testing.T) {
+	dir := t.TempDir()
+	assert.Empty(t, cover.ModulePath(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/myapps\n\ngo 1.24\n"), 0o644))
+	assert.Equal(t, "example.com/myapps", cover.ModulePath(dir))
+}
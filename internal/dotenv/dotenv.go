@@ -0,0 +1,133 @@
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Entry is a variable read from a dotenv file.
+type Entry struct {
+	Key   string
+	Value string
+}
+
+// Read parses the dotenv file at path.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	entries, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// Parse parses KEY=VALUE lines. Empty lines and lines starting with # are
+// ignored, and keys may be preceded by "export". Values may be quoted:
+// single quoted values are literal, double quoted values support \n, \t, \"
+// and \\ escapes, and may span multiple lines, such as PEM encoded keys.
+// Quoted values end at their first closing quote, which may be followed by a
+// # comment. Unquoted values end at a " #" comment.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		start := lineNum
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", start)
+		}
+
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: invalid key %q", start, key)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			// Read more lines until the closing quote.
+			end := closingDoubleQuote(value)
+			for end == -1 {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+				}
+				lineNum++
+				value += "\n" + scanner.Text()
+				end = closingDoubleQuote(value)
+			}
+			if !isComment(value[end+1:]) {
+				return nil, fmt.Errorf("line %d: unexpected text after the quoted value", start)
+			}
+			value = unescape(value[1:end])
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'") + 1
+			if end == 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", start)
+			}
+			if !isComment(value[end+1:]) {
+				return nil, fmt.Errorf("line %d: unexpected text after the quoted value", start)
+			}
+			value = value[1:end]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		entries = append(entries, Entry{Key: key, Value: value})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// closingDoubleQuote returns the index of the first unescaped closing quote
+// of the double quoted value starting at s, or -1 when there is none.
+func closingDoubleQuote(s string) int {
+	escaped := false
+	for i, r := range s[1:] {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// isComment reports whether the rest of a line after a quoted value is empty
+// or a comment.
+func isComment(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || strings.HasPrefix(s, "#")
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(s)
+}
@@ -0,0 +1,315 @@
+package fuzz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/tempestdx/cli/internal/schema"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Kinds of failures.
+const (
+	KindPanic      = "panic"
+	KindInternal   = "internal error"
+	KindTimeout    = "timeout"
+	KindProperties = "invalid properties"
+	KindError      = "unexpected error"
+)
+
+// defaultTimeout is the timeout of requests when Options.Timeout is not set.
+const defaultTimeout = 10 * time.Second
+
+// panicWait is how long to wait for the app to log a panic, after a request
+// failed without a response.
+const panicWait = 250 * time.Millisecond
+
+// Options configure a fuzzing run.
+type Options struct {
+	// Operation is create or update.
+	Operation appv1.ResourceOperation
+	Type      string
+	// ExternalID is the resource updated by update operations.
+	ExternalID       string
+	InputSchema      *schema.Schema
+	PropertiesSchema *schema.Schema
+	Metadata         *appv1.Metadata
+	Env              []*appv1.EnvironmentVariable
+
+	Runs    int
+	Seed    uint64
+	Timeout time.Duration
+	// Shrinks is the number of candidates tried when shrinking a failing
+	// input.
+	Shrinks int
+	// Delete deletes the resources created by the run.
+	Delete bool
+	// Panics returns the panic logged by the app since it was last called,
+	// if any.
+	Panics func() string
+	// Progress is called after each run.
+	Progress func(run int, f *Failure)
+}
+
+// Failure is an input the app failed on.
+type Failure struct {
+	Kind    string         `json:"kind" yaml:"kind"`
+	Message string         `json:"message" yaml:"message"`
+	Run     int            `json:"run" yaml:"run"`
+	Input   map[string]any `json:"input" yaml:"input"`
+	// Shrunk is the smallest input found to fail the same way.
+	Shrunk map[string]any `json:"shrunk" yaml:"shrunk"`
+}
+
+// Result of a fuzzing run.
+type Result struct {
+	Seed uint64 `json:"seed" yaml:"seed"`
+	Runs int    `json:"runs" yaml:"runs"`
+	// Accepted counts the inputs the operation succeeded with.
+	Accepted int `json:"accepted" yaml:"accepted"`
+	// Rejected counts the inputs the app rejected as invalid.
+	Rejected int `json:"rejected" yaml:"rejected"`
+	// Failed counts the inputs the app failed on, including the ones failing
+	// like a previous input.
+	Failed   int        `json:"failed" yaml:"failed"`
+	Failures []*Failure `json:"failures" yaml:"failures"`
+	// Created lists the resources created and not deleted.
+	Created []string `json:"created,omitempty" yaml:"created,omitempty"`
+}
+
+type fuzzer struct {
+	client  appv1connect.AppServiceClient
+	opts    Options
+	created []string
+}
+
+// Run sends opts.Runs inputs generated from the input schema to the app.
+// Panics, internal errors, timeouts and properties which do not match the
+// properties schema are failures, and their inputs are shrunk. Failures are
+// reported once per kind and message.
+func Run(ctx context.Context, client appv1connect.AppServiceClient, opts Options) *Result {
+	f := &fuzzer{
+		client: client,
+		opts:   opts,
+	}
+	if f.opts.Panics == nil {
+		f.opts.Panics = func() string { return "" }
+	}
+	if f.opts.Timeout <= 0 {
+		f.opts.Timeout = defaultTimeout
+	}
+
+	result := &Result{
+		Seed: opts.Seed,
+		Runs: opts.Runs,
+	}
+
+	g := NewGenerator(opts.Seed)
+	seen := make(map[string]bool)
+	for run := 1; run <= opts.Runs; run++ {
+		input := g.Input(opts.InputSchema.Raw())
+
+		failure, rejected := f.exec(ctx, input)
+		switch {
+		case failure != nil:
+			result.Failed++
+			failure.Run = run
+			key := failure.Kind + "\x00" + failure.Message
+			if seen[key] {
+				failure = nil
+				break
+			}
+			seen[key] = true
+
+			failure.Shrunk = Shrink(input, opts.Shrinks, func(candidate map[string]any) bool {
+				f, _ := f.exec(ctx, candidate)
+				return f != nil && f.Kind == failure.Kind
+			})
+			result.Failures = append(result.Failures, failure)
+		case rejected:
+			result.Rejected++
+		default:
+			result.Accepted++
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(run, failure)
+		}
+
+		if ctx.Err() != nil {
+			result.Runs = run
+			break
+		}
+	}
+
+	if opts.Delete {
+		for _, id := range f.created {
+			if err := f.delete(ctx, id); err != nil {
+				result.Created = append(result.Created, id)
+			}
+		}
+	} else {
+		result.Created = f.created
+	}
+
+	return result
+}
+
+// exec runs the operation with input. It returns the failure, if any, and
+// whether the app rejected the input as invalid.
+func (f *fuzzer) exec(ctx context.Context, input map[string]any) (*Failure, bool) {
+	in, err := structpb.NewStruct(input)
+	if err != nil {
+		return &Failure{Kind: KindError, Message: err.Error(), Input: input}, false
+	}
+
+	// Forget panics logged by previous runs.
+	f.opts.Panics()
+
+	callCtx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+
+	res, err := f.client.ExecuteResourceOperation(callCtx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+		Operation: f.opts.Operation,
+		Resource: &appv1.Resource{
+			Type:       f.opts.Type,
+			ExternalId: f.opts.ExternalID,
+		},
+		Input:                in,
+		Metadata:             f.opts.Metadata,
+		EnvironmentVariables: f.opts.Env,
+	}))
+	if err == nil {
+		if f.opts.Operation == appv1.ResourceOperation_RESOURCE_OPERATION_CREATE && res.Msg.Resource.GetExternalId() != "" {
+			f.created = append(f.created, res.Msg.Resource.ExternalId)
+		}
+
+		if f.opts.PropertiesSchema != nil {
+			if err := f.opts.PropertiesSchema.Validate(res.Msg.Resource.GetProperties().AsMap()); err != nil {
+				return &Failure{Kind: KindProperties, Message: err.Error(), Input: input}, false
+			}
+		}
+
+		return nil, false
+	}
+
+	code := connect.CodeOf(err)
+	switch {
+	case code == connect.CodeDeadlineExceeded || errors.Is(err, context.DeadlineExceeded):
+		return &Failure{Kind: KindTimeout, Message: fmt.Sprintf("no response within %s", f.opts.Timeout), Input: input}, false
+	case code == connect.CodeInvalidArgument:
+		return nil, true
+	case code == connect.CodeInternal && strings.Contains(err.Error(), "output:"):
+		// The SDK validates the properties of the resources returned by
+		// the handlers, e.g. "validate create output: ...".
+		return &Failure{Kind: KindProperties, Message: connectMessage(err), Input: input}, false
+	case code == connect.CodeInternal:
+		return &Failure{Kind: KindInternal, Message: connectMessage(err), Input: input}, false
+	}
+
+	// A panic in a handler closes the connection without a response.
+	deadline := time.Now().Add(panicWait)
+	for {
+		if p := f.opts.Panics(); p != "" {
+			return &Failure{Kind: KindPanic, Message: p, Input: input}, false
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return &Failure{Kind: KindError, Message: err.Error(), Input: input}, false
+}
+
+func (f *fuzzer) delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, f.opts.Timeout)
+	defer cancel()
+
+	_, err := f.client.ExecuteResourceOperation(ctx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+		Operation: appv1.ResourceOperation_RESOURCE_OPERATION_DELETE,
+		Resource: &appv1.Resource{
+			Type:       f.opts.Type,
+			ExternalId: id,
+		},
+		Metadata:             f.opts.Metadata,
+		EnvironmentVariables: f.opts.Env,
+	}))
+
+	return err
+}
+
+func connectMessage(err error) string {
+	var ce *connect.Error
+	if errors.As(err, &ce) {
+		return ce.Message()
+	}
+
+	return err.Error()
+}
+
+// PanicDetector is an io.Writer for the logs of an app, which records the
+// panics logged by the app.
+type PanicDetector struct {
+	mu     sync.Mutex
+	buf    []byte
+	panics []string
+}
+
+func (d *PanicDetector) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.buf = append(d.buf, p...)
+	for {
+		i := bytes.IndexByte(d.buf, '\n')
+		if i == -1 {
+			break
+		}
+
+		line := string(d.buf[:i])
+		d.buf = d.buf[i+1:]
+
+		// net/http logs "http: panic serving <addr>: <value>" when a
+		// handler panics, and the runtime "panic: <value>" when the app
+		// crashes. Only the value is kept: the address of the client changes
+		// with each connection.
+		var msg string
+		if _, rest, ok := strings.Cut(line, "panic serving "); ok {
+			_, msg, _ = strings.Cut(rest, ": ")
+		} else if rest, ok := strings.CutPrefix(line, "panic: "); ok {
+			msg = rest
+		}
+		if msg == "" {
+			continue
+		}
+
+		d.panics = append(d.panics, msg)
+	}
+
+	return len(p), nil
+}
+
+// Take returns the value of the first panic recorded since the last call, or
+// an empty string.
+func (d *PanicDetector) Take() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.panics) == 0 {
+		return ""
+	}
+
+	p := d.panics[0]
+	d.panics = nil
+
+	return p
+}
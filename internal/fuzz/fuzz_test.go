@@ -0,0 +1,200 @@
+package fuzz_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/fuzz"
+	"github.com/tempestdx/cli/internal/schema"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"github.com/tempestdx/sdk-go/app"
+)
+
+var inputSchema = []byte(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 200},
+		"size": {"type": "integer", "minimum": 1},
+		"ratio": {"type": "number", "exclusiveMinimum": 0, "maximum": 1},
+		"tier": {"enum": ["free", "paid"]},
+		"code": {"type": "string", "pattern": "^[A-Z]{3}-[0-9]{2,4}$"},
+		"tags": {"type": "array", "items": {"type": "string", "maxLength": 5}, "maxItems": 3},
+		"email": {"type": "string", "format": "email"}
+	},
+	"required": ["name", "size"],
+	"additionalProperties": false
+}`)
+
+// nestedSchema has an object property, which apps cannot declare, but which
+// the generator supports.
+var nestedSchema = []byte(`{
+	"type": "object",
+	"properties": {
+		"owner": {
+			"type": "object",
+			"properties": {
+				"email": {"type": "string", "format": "email"},
+				"teams": {"type": "array", "items": {"type": "string"}, "minItems": 1}
+			},
+			"required": ["email", "teams"]
+		}
+	},
+	"required": ["owner"]
+}`)
+
+func newSchema(t *testing.T, b []byte) *schema.Schema {
+	t.Helper()
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal(b, &raw))
+
+	s, err := schema.New(raw)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestGenerator(t *testing.T) {
+	s := newSchema(t, inputSchema)
+
+	g := fuzz.NewGenerator(1)
+	missingOptional := false
+	for range 500 {
+		input := g.Input(s.Raw())
+		require.NoError(t, s.Validate(input), "%v", input)
+
+		if _, ok := input["tier"]; !ok {
+			missingOptional = true
+		}
+	}
+	assert.True(t, missingOptional, "optional properties are left out")
+
+	nested := newSchema(t, nestedSchema)
+	for range 100 {
+		input := g.Input(nested.Raw())
+		require.NoError(t, nested.Validate(input), "%v", input)
+	}
+
+	// The same seed generates the same inputs.
+	a, b := fuzz.NewGenerator(42), fuzz.NewGenerator(42)
+	for range 20 {
+		assert.Equal(t, a.Input(s.Raw()), b.Input(s.Raw()))
+	}
+}
+
+func TestShrink(t *testing.T) {
+	input := map[string]any{
+		"name": "abcüdef",
+		"size": float64(12345),
+		"tags": []any{"a", "b", "c"},
+	}
+
+	shrunk := fuzz.Shrink(input, 1000, func(in map[string]any) bool {
+		name, _ := in["name"].(string)
+		return utf8.RuneCountInString(name) != len(name)
+	})
+
+	assert.Equal(t, map[string]any{"name": "ü"}, shrunk)
+}
+
+// newClient starts an app which panics on sizes above 1000, and fails on
+// names longer than 100 characters.
+func newClient(t *testing.T, detector *fuzz.PanicDetector) (appv1connect.AppServiceClient, map[string]bool) {
+	t.Helper()
+
+	created := make(map[string]bool)
+	var nextID int
+
+	rd := app.ResourceDefinition{
+		Type:             "item",
+		DisplayName:      "Item",
+		PropertiesSchema: app.MustParseJSONSchema([]byte(`{"type": "object", "properties": {}}`)),
+	}
+	rd.CreateFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		if req.Input["size"].(float64) > 1000 {
+			panic("size too large")
+		}
+		if utf8.RuneCountInString(req.Input["name"].(string)) > 100 {
+			return nil, errors.New("name too long")
+		}
+
+		nextID++
+		id := fmt.Sprintf("item-%d", nextID)
+		created[id] = true
+		return &app.OperationResponse{Resource: &app.Resource{ExternalID: id, Type: "item"}}, nil
+	}, app.MustParseJSONSchema(inputSchema))
+	rd.DeleteFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		delete(created, req.Resource.ExternalID)
+		return &app.OperationResponse{Resource: &app.Resource{ExternalID: req.Resource.ExternalID, Type: "item"}}, nil
+	})
+
+	_, handler := appv1connect.NewAppServiceHandler(app.New(app.WithResourceDefinition(rd)))
+	srv := httptest.NewUnstartedServer(handler)
+	// net/http logs the panics of handlers.
+	srv.Config.ErrorLog = log.New(detector, "", 0)
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return appv1connect.NewAppServiceClient(srv.Client(), srv.URL), created
+}
+
+func TestRun(t *testing.T) {
+	detector := &fuzz.PanicDetector{}
+	client, created := newClient(t, detector)
+
+	result := fuzz.Run(context.Background(), client, fuzz.Options{
+		Operation:   appv1.ResourceOperation_RESOURCE_OPERATION_CREATE,
+		Type:        "item",
+		InputSchema: newSchema(t, inputSchema),
+		Runs:        100,
+		Seed:        7,
+		Timeout:     5 * time.Second,
+		Shrinks:     1000,
+		Delete:      true,
+		Panics:      detector.Take,
+	})
+
+	require.Len(t, result.Failures, 2)
+	assert.Equal(t, 100, result.Runs)
+	assert.Equal(t, 100, result.Accepted+result.Rejected+result.Failed)
+
+	kinds := make(map[string]*fuzz.Failure)
+	for _, f := range result.Failures {
+		kinds[f.Kind] = f
+	}
+
+	require.Contains(t, kinds, fuzz.KindPanic)
+	p := kinds[fuzz.KindPanic]
+	assert.Equal(t, "size too large", p.Message)
+	assert.Len(t, p.Shrunk, 2, "only the required properties are left")
+	assert.Equal(t, float64(1001), p.Shrunk["size"])
+
+	require.Contains(t, kinds, fuzz.KindInternal)
+	i := kinds[fuzz.KindInternal]
+	assert.Equal(t, "create resource: name too long", i.Message)
+	assert.Equal(t, 101, utf8.RuneCountInString(i.Shrunk["name"].(string)))
+
+	assert.Empty(t, created, "created resources are deleted")
+	assert.Empty(t, result.Created)
+}
+
+func TestPanicDetector(t *testing.T) {
+	d := &fuzz.PanicDetector{}
+	assert.Empty(t, d.Take())
+
+	_, _ = d.Write([]byte("starting\nhttp: panic serving 127.0.0.1:51234: boom\ngorout"))
+	_, _ = d.Write([]byte("ine 7 [running]:\npanic: runtime error: index out of range\n"))
+
+	assert.Equal(t, "boom", d.Take())
+	assert.Empty(t, d.Take(), "Take forgets the recorded panics")
+}
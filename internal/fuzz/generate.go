@@ -0,0 +1,342 @@
+package fuzz
+
+import (
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDepth bounds the nesting of generated objects and arrays.
+const maxDepth = 4
+
+// Strings which often break apps: unicode, whitespace, quotes and markup.
+var edgeStrings = []string{
+	"ünïcödé",
+	"日本語のテキスト",
+	"🚀🔥👍🏽",
+	"مرحبا بالعالم",
+	"e\u0301\u200b\u200d\ufeff",
+	" ",
+	"\t\n",
+	`"quoted" 'single' \backslash`,
+	"<script>alert(1)</script>",
+	"'; DROP TABLE resources; --",
+	"${HOME} $(id) `id`",
+	"null",
+	"0",
+	"-1",
+	"true",
+}
+
+// Runes strings of a given length are built from.
+var alphabet = []rune("abcXYZ019 _-.ü日🚀")
+
+// Samples of strings in the formats apps commonly declare.
+var formatStrings = map[string][]string{
+	"email":     {"test@example.com", "a@b.co", "first.last+tag@sub.example.org", "ü@example.com"},
+	"uri":       {"https://example.com", "http://localhost:8080/path?q=1#f", "https://example.com/" + strings.Repeat("a", 200)},
+	"url":       {"https://example.com", "http://localhost:8080/path?q=1#f"},
+	"date-time": {"2024-01-01T00:00:00Z", "1970-01-01T00:00:00Z", "2038-01-19T03:14:08+01:00", "2024-02-29T23:59:59.999Z"},
+	"date":      {"2024-01-01", "1970-01-01", "2024-02-29", "9999-12-31"},
+	"time":      {"00:00:00Z", "23:59:59+01:00"},
+	"uuid":      {"00000000-0000-0000-0000-000000000000", "123e4567-e89b-12d3-a456-426614174000"},
+	"ipv4":      {"127.0.0.1", "0.0.0.0", "255.255.255.255"},
+	"ipv6":      {"::1", "2001:db8::ff00:42:8329"},
+	"hostname":  {"example.com", "localhost", "a-b.c-d.example"},
+}
+
+// Generator generates values matching JSON schemas, biased towards the edge
+// cases of the schema: boundary lengths and values, missing optional
+// properties, and unusual strings.
+type Generator struct {
+	r *rand.Rand
+}
+
+// NewGenerator returns a generator which always generates the same values for
+// the same seed.
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{
+		r: rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Input generates an object matching the raw object schema.
+func (g *Generator) Input(raw map[string]any) map[string]any {
+	obj, _ := g.object(raw, 0).(map[string]any)
+	if obj == nil {
+		obj = map[string]any{}
+	}
+
+	return obj
+}
+
+func (g *Generator) pick(n int) int {
+	return g.r.IntN(n)
+}
+
+func (g *Generator) value(prop map[string]any, depth int) any {
+	if v, ok := prop["const"]; ok {
+		return v
+	}
+	if enum, ok := prop["enum"].([]any); ok && len(enum) > 0 {
+		return enum[g.pick(len(enum))]
+	}
+	if examples, ok := prop["examples"].([]any); ok && len(examples) > 0 && g.pick(4) == 0 {
+		return examples[g.pick(len(examples))]
+	}
+	if def, ok := prop["default"]; ok && g.pick(4) == 0 {
+		return def
+	}
+	if variants, ok := prop["oneOf"].([]any); ok && len(variants) > 0 {
+		v, _ := variants[g.pick(len(variants))].(map[string]any)
+		return g.value(v, depth)
+	}
+	if variants, ok := prop["anyOf"].([]any); ok && len(variants) > 0 {
+		v, _ := variants[g.pick(len(variants))].(map[string]any)
+		return g.value(v, depth)
+	}
+
+	switch g.typeOf(prop) {
+	case "object":
+		return g.object(prop, depth)
+	case "array":
+		return g.array(prop, depth)
+	case "string":
+		return g.string(prop)
+	case "integer":
+		return g.number(prop, true)
+	case "number":
+		return g.number(prop, false)
+	case "boolean":
+		return g.pick(2) == 0
+	case "null":
+		return nil
+	}
+
+	// Any value is accepted.
+	switch g.pick(3) {
+	case 0:
+		return g.string(nil)
+	case 1:
+		return g.number(nil, false)
+	default:
+		return g.pick(2) == 0
+	}
+}
+
+// typeOf returns the type to generate for prop, picking one when several are
+// accepted.
+func (g *Generator) typeOf(prop map[string]any) string {
+	switch t := prop["type"].(type) {
+	case string:
+		return t
+	case []any:
+		if len(t) > 0 {
+			s, _ := t[g.pick(len(t))].(string)
+			return s
+		}
+	}
+
+	if _, ok := prop["properties"]; ok {
+		return "object"
+	}
+	if _, ok := prop["items"]; ok {
+		return "array"
+	}
+
+	return ""
+}
+
+func (g *Generator) object(prop map[string]any, depth int) any {
+	properties, _ := prop["properties"].(map[string]any)
+	required := make(map[string]bool)
+	if r, ok := prop["required"].([]any); ok {
+		for _, name := range r {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	obj := make(map[string]any)
+	// Iterate in a stable order, so that a seed generates the same values.
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		// Optional properties are left out half of the time, and always
+		// beyond the maximum depth.
+		if !required[name] && (depth >= maxDepth || g.pick(2) == 0) {
+			continue
+		}
+
+		p, _ := properties[name].(map[string]any)
+		obj[name] = g.value(p, depth+1)
+	}
+
+	return obj
+}
+
+func (g *Generator) array(prop map[string]any, depth int) []any {
+	minItems := intValue(prop["minItems"], 0)
+	maxItems := intValue(prop["maxItems"], minItems+3)
+	if depth >= maxDepth {
+		maxItems = minItems
+	}
+
+	n := minItems
+	if maxItems > minItems {
+		n += g.pick(min(maxItems-minItems, 4) + 1)
+	}
+
+	items, _ := prop["items"].(map[string]any)
+	a := make([]any, 0, n)
+	for range n {
+		a = append(a, g.value(items, depth+1))
+	}
+
+	return a
+}
+
+func (g *Generator) string(prop map[string]any) string {
+	minLength := intValue(prop["minLength"], 0)
+	maxLength := intValue(prop["maxLength"], -1)
+
+	fits := func(s string) bool {
+		n := utf8.RuneCountInString(s)
+		return n >= minLength && (maxLength < 0 || n <= maxLength)
+	}
+
+	if pattern, ok := prop["pattern"].(string); ok {
+		// Strings which do not match the pattern are mostly rejected, so
+		// matching strings are generated, when they fit the length
+		// constraints.
+		if s, err := g.matching(pattern); err == nil && fits(s) {
+			return s
+		}
+	}
+
+	if format, ok := prop["format"].(string); ok {
+		if samples, ok := formatStrings[format]; ok {
+			return samples[g.pick(len(samples))]
+		}
+	}
+
+	switch g.pick(3) {
+	case 0:
+		// An edge case string, when it fits the length constraints.
+		s := edgeStrings[g.pick(len(edgeStrings))]
+		if fits(s) {
+			return s
+		}
+	case 1:
+		// A random string.
+		upper := maxLength
+		if upper < minLength {
+			upper = minLength + 32
+		}
+		return g.runes(minLength + g.pick(upper-minLength+1))
+	}
+
+	// A string of boundary length.
+	lengths := []int{minLength, minLength + 1}
+	if maxLength >= 0 {
+		lengths = append(lengths, maxLength, max(maxLength-1, minLength))
+	} else {
+		lengths = append(lengths, minLength+1000)
+	}
+
+	n := lengths[g.pick(len(lengths))]
+	if maxLength >= 0 {
+		n = min(n, maxLength)
+	}
+
+	return g.runes(n)
+}
+
+func (g *Generator) runes(n int) string {
+	var b strings.Builder
+	for range n {
+		b.WriteRune(alphabet[g.pick(len(alphabet))])
+	}
+
+	return b.String()
+}
+
+func (g *Generator) number(prop map[string]any, integer bool) float64 {
+	lower, upper := math.Inf(-1), math.Inf(1)
+	if v, ok := prop["minimum"].(float64); ok {
+		lower = v
+	}
+	if v, ok := prop["exclusiveMinimum"].(float64); ok {
+		lower = math.Max(lower, nextAbove(v, integer))
+	}
+	if v, ok := prop["maximum"].(float64); ok {
+		upper = v
+	}
+	if v, ok := prop["exclusiveMaximum"].(float64); ok {
+		upper = math.Min(upper, nextBelow(v, integer))
+	}
+	if integer {
+		lower, upper = math.Ceil(lower), math.Floor(upper)
+	}
+
+	candidates := []float64{0, 1, -1, 1e15, -1e15}
+	if !integer {
+		candidates = append(candidates, 0.5, -0.000001, 1234.5678)
+	}
+	if !math.IsInf(lower, 0) {
+		candidates = append(candidates, lower, lower+1)
+	}
+	if !math.IsInf(upper, 0) {
+		candidates = append(candidates, upper, upper-1)
+	}
+	if !math.IsInf(lower, 0) && !math.IsInf(upper, 0) {
+		mid := lower + (upper-lower)*g.r.Float64()
+		if integer {
+			mid = math.Round(mid)
+		}
+		candidates = append(candidates, mid)
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(n float64) bool {
+		return n < lower || n > upper
+	})
+	if len(candidates) == 0 {
+		return lower
+	}
+
+	n := candidates[g.pick(len(candidates))]
+	if m, ok := prop["multipleOf"].(float64); ok && m > 0 {
+		n = math.Ceil(n/m) * m
+		if n > upper {
+			n -= m
+		}
+	}
+
+	return n
+}
+
+func nextAbove(v float64, integer bool) float64 {
+	if integer {
+		return math.Floor(v) + 1
+	}
+
+	return math.Nextafter(v, math.Inf(1))
+}
+
+func nextBelow(v float64, integer bool) float64 {
+	if integer {
+		return math.Ceil(v) - 1
+	}
+
+	return math.Nextafter(v, math.Inf(-1))
+}
+
+func intValue(v any, def int) int {
+	if n, ok := v.(float64); ok {
+		return int(n)
+	}
+
+	return def
+}
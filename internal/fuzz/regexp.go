@@ -0,0 +1,94 @@
+package fuzz
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// maxRepeat bounds the repetitions of unbounded repeats, such as x* and x+,
+// except for the occasional long repeat of longRepeat repetitions.
+const (
+	maxRepeat  = 4
+	longRepeat = 300
+)
+
+// matching generates a string matching the regular expression pattern. JSON
+// schema patterns are not anchored, but anchors are honored by generating
+// nothing around the match.
+func (g *Generator) matching(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("parse pattern: %w", err)
+	}
+
+	var b strings.Builder
+	g.regexp(&b, re.Simplify())
+
+	return b.String(), nil
+}
+
+func (g *Generator) regexp(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.pick(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		// Rune holds pairs of inclusive ranges.
+		if len(re.Rune) == 0 {
+			return
+		}
+		i := g.pick(len(re.Rune)/2) * 2
+		lo, hi := re.Rune[i], re.Rune[i+1]
+		b.WriteRune(lo + rune(g.pick(int(min(hi-lo, 0xff))+1)))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		b.WriteRune(alphabet[g.pick(len(alphabet))])
+	case syntax.OpCapture:
+		g.regexp(b, re.Sub[0])
+	case syntax.OpStar:
+		g.repeat(b, re.Sub[0], 0, g.unbounded(0))
+	case syntax.OpPlus:
+		g.repeat(b, re.Sub[0], 1, g.unbounded(1))
+	case syntax.OpQuest:
+		g.repeat(b, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		upper := re.Max
+		if upper < 0 {
+			upper = g.unbounded(re.Min)
+		}
+		g.repeat(b, re.Sub[0], re.Min, upper)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.regexp(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.regexp(b, re.Sub[g.pick(len(re.Sub))])
+	}
+	// Anchors, word boundaries and empty matches generate nothing.
+}
+
+func (g *Generator) repeat(b *strings.Builder, re *syntax.Regexp, lower, upper int) {
+	n := lower
+	if upper > lower {
+		n += g.pick(upper - lower + 1)
+	}
+
+	for range n {
+		g.regexp(b, re)
+	}
+}
+
+// unbounded returns the upper bound of the repetitions of an unbounded repeat
+// of at least lower repetitions.
+func (g *Generator) unbounded(lower int) int {
+	if g.pick(8) == 0 {
+		return lower + longRepeat
+	}
+
+	return lower + maxRepeat
+}
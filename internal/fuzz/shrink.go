@@ -0,0 +1,121 @@
+package fuzz
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+// Shrink returns the smallest input derived from input for which fails
+// returns true, trying at most attempts candidates. Candidates leave out
+// properties and array items, and shorten strings and numbers, one change at
+// a time.
+func Shrink(input map[string]any, attempts int, fails func(map[string]any) bool) map[string]any {
+	current := input
+	for attempts > 0 {
+		shrunk := false
+		for _, c := range shrinkValue(current) {
+			if attempts == 0 {
+				break
+			}
+			attempts--
+
+			candidate := c.(map[string]any)
+			if fails(candidate) {
+				current = candidate
+				shrunk = true
+				break
+			}
+		}
+
+		if !shrunk {
+			break
+		}
+	}
+
+	return current
+}
+
+// shrinkValue returns the values one step smaller than v, the smallest first.
+func shrinkValue(v any) []any {
+	switch v := v.(type) {
+	case map[string]any:
+		var candidates []any
+		keys := slices.Sorted(maps.Keys(v))
+		for _, k := range keys {
+			c := maps.Clone(v)
+			delete(c, k)
+			candidates = append(candidates, c)
+		}
+		for _, k := range keys {
+			for _, s := range shrinkValue(v[k]) {
+				c := maps.Clone(v)
+				c[k] = s
+				candidates = append(candidates, c)
+			}
+		}
+		return candidates
+	case []any:
+		var candidates []any
+		if len(v) > 1 {
+			candidates = append(candidates, slices.Clone(v[:len(v)/2]))
+		}
+		for i := range v {
+			candidates = append(candidates, slices.Delete(slices.Clone(v), i, i+1))
+		}
+		for i := range v {
+			for _, s := range shrinkValue(v[i]) {
+				c := slices.Clone(v)
+				c[i] = s
+				candidates = append(candidates, c)
+			}
+		}
+		return candidates
+	case string:
+		r := []rune(v)
+		if len(r) == 0 {
+			return nil
+		}
+		candidates := []any{""}
+		for _, n := range cuts(len(r)) {
+			candidates = append(candidates, string(r[:n]))
+		}
+		if len(r) > 1 {
+			candidates = append(candidates, string(r[len(r)/2:]))
+		}
+		return candidates
+	case float64:
+		if v == 0 {
+			return nil
+		}
+		candidates := []any{0.0}
+		if v != math.Trunc(v) {
+			candidates = append(candidates, math.Trunc(v))
+		} else if math.Abs(v) <= 1<<53 {
+			for _, n := range cuts(int(math.Abs(v))) {
+				candidates = append(candidates, math.Copysign(float64(n), v))
+			}
+		}
+		return candidates
+	case bool:
+		if v {
+			return []any{false}
+		}
+	}
+
+	return nil
+}
+
+// cuts returns sizes between 1 and n-1 to shrink a size n to, the smallest
+// first: n/2, 3n/4, 7n/8... and n-1, so that shrinking converges on the
+// smallest failing size in a logarithmic number of steps.
+func cuts(n int) []int {
+	var sizes []int
+	for d := n / 2; d > 0; d /= 2 {
+		if size := n - d; size > 0 && (len(sizes) == 0 || sizes[len(sizes)-1] != size) {
+			sizes = append(sizes, size)
+		}
+	}
+
+	return sizes
+}
@@ -0,0 +1,286 @@
+// Package oauth implements the OAuth 2.0 Device Authorization Grant (RFC 8628)
+// and the refresh token grant used by `tempest auth login --web`.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	grantTypeDeviceCode   = "urn:ietf:params:oauth:grant-type:device_code"
+	grantTypeRefreshToken = "refresh_token"
+
+	// The polling interval when the server does not provide one (RFC 8628, section 3.2).
+	defaultInterval = 5 * time.Second
+	// The interval increase requested by a slow_down error (RFC 8628, section 3.5).
+	slowDownIncrease = 5 * time.Second
+)
+
+var (
+	ErrAccessDenied = errors.New("authorization request was denied")
+	ErrExpiredToken = errors.New("device code expired before the request was authorized")
+
+	// Replaced in tests to avoid waiting for the polling interval.
+	timeAfter = time.After
+)
+
+type Config struct {
+	// The client identifier of the CLI registered with the authorization server.
+	ClientID string
+	// The device authorization endpoint.
+	DeviceAuthURL string
+	// The token endpoint.
+	TokenURL string
+	// The scopes to request. Optional.
+	Scopes []string
+	// The HTTP client used to reach the authorization server. Defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// DeviceCode is the response of the device authorization endpoint.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// Lifetime of the device code, in seconds.
+	ExpiresIn int `json:"expires_in"`
+	// Minimum polling interval, in seconds.
+	Interval int `json:"interval,omitempty"`
+}
+
+// Token is an access token, along with the refresh token used to renew it.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+}
+
+// tokenResponse is the response of the token endpoint, including errors.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Expired reports whether the access token is expired, or about to expire.
+func (t *Token) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().Add(30*time.Second).After(t.Expiry)
+}
+
+// Encode encodes the token for storage in a secret.TokenStore.
+func (t *Token) Encode() (string, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// DecodeToken decodes a token stored with Encode. It returns false if s is
+// not an encoded token, for example a plain API key.
+func DecodeToken(s string) (*Token, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return nil, false
+	}
+
+	var t Token
+	if err := json.Unmarshal([]byte(s), &t); err != nil || t.AccessToken == "" {
+		return nil, false
+	}
+
+	return &t, true
+}
+
+// RequestDeviceCode starts the device authorization flow.
+func (c *Config) RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{
+		"client_id": {c.ClientID},
+	}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	res, err := c.post(ctx, c.DeviceAuthURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("device authorization: %s: %s", res.Status, strings.TrimSpace(string(b)))
+	}
+
+	var code DeviceCode
+	if err := json.Unmarshal(b, &code); err != nil {
+		return nil, fmt.Errorf("decode device authorization response: %w", err)
+	}
+
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return nil, errors.New("device authorization: incomplete response from the authorization server")
+	}
+
+	return &code, nil
+}
+
+// PollToken polls the token endpoint until the user authorizes the device,
+// denies the request, or the device code expires.
+func (c *Config) PollToken(ctx context.Context, code *DeviceCode) (*Token, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	var expired <-chan time.Time
+	if code.ExpiresIn > 0 {
+		expired = timeAfter(time.Duration(code.ExpiresIn) * time.Second)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-expired:
+			return nil, ErrExpiredToken
+		case <-timeAfter(interval):
+		}
+
+		tr, err := c.requestToken(ctx, url.Values{
+			"grant_type":  {grantTypeDeviceCode},
+			"device_code": {code.DeviceCode},
+			"client_id":   {c.ClientID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch tr.Error {
+		case "":
+			return tr.token(), nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += slowDownIncrease
+			continue
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrExpiredToken
+		default:
+			return nil, tr.err()
+		}
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token. When the server
+// does not rotate the refresh token, the previous one is kept.
+func (c *Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	if refreshToken == "" {
+		return nil, errors.New("no refresh token available")
+	}
+
+	tr, err := c.requestToken(ctx, url.Values{
+		"grant_type":    {grantTypeRefreshToken},
+		"refresh_token": {refreshToken},
+		"client_id":     {c.ClientID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tr.Error != "" {
+		return nil, tr.err()
+	}
+
+	t := tr.token()
+	if t.RefreshToken == "" {
+		t.RefreshToken = refreshToken
+	}
+
+	return t, nil
+}
+
+func (c *Config) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	res, err := c.post(ctx, c.TokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors are returned with a 400 status and a JSON body (RFC 6749, section 5.2).
+	var tr tokenResponse
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt == "application/json" {
+		if err := json.Unmarshal(b, &tr); err != nil {
+			return nil, fmt.Errorf("decode token response: %w", err)
+		}
+	}
+
+	if tr.Error == "" && (res.StatusCode != http.StatusOK || tr.AccessToken == "") {
+		return nil, fmt.Errorf("token request: %s: %s", res.Status, strings.TrimSpace(string(b)))
+	}
+
+	return &tr, nil
+}
+
+func (c *Config) post(ctx context.Context, endpoint string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
+
+func (tr *tokenResponse) token() *Token {
+	t := &Token{
+		AccessToken:  tr.AccessToken,
+		RefreshToken: tr.RefreshToken,
+		TokenType:    tr.TokenType,
+	}
+	if tr.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+
+	return t
+}
+
+func (tr *tokenResponse) err() error {
+	if tr.ErrorDescription != "" {
+		return fmt.Errorf("token request: %s: %s", tr.Error, tr.ErrorDescription)
+	}
+
+	return fmt.Errorf("token request: %s", tr.Error)
+}
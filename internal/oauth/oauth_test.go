@@ -0,0 +1,193 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authServer is a stand-in authorization server. The device code is approved
+// after the token endpoint has been polled approvedAfter times.
+type authServer struct {
+	mu            sync.Mutex
+	polls         int
+	approvedAfter int
+	deny          bool
+	slowDown      bool
+	refreshed     []string
+}
+
+func (s *authServer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/device/code", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "tempest-cli", r.PostForm.Get("client_id"))
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":      "dev_123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       600,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.PostForm.Get("grant_type") {
+		case grantTypeDeviceCode:
+			assert.Equal(t, "dev_123", r.PostForm.Get("device_code"))
+
+			s.polls++
+			switch {
+			case s.deny:
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "access_denied"})
+			case s.slowDown && s.polls == 1:
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "slow_down"})
+			case s.polls <= s.approvedAfter:
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "authorization_pending"})
+			default:
+				writeJSON(w, http.StatusOK, map[string]any{
+					"access_token":  "access_1",
+					"refresh_token": "refresh_1",
+					"token_type":    "Bearer",
+					"expires_in":    3600,
+				})
+			}
+		case grantTypeRefreshToken:
+			s.refreshed = append(s.refreshed, r.PostForm.Get("refresh_token"))
+			if r.PostForm.Get("refresh_token") != "refresh_1" {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant", "error_description": "refresh token revoked"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{
+				"access_token": "access_2",
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unsupported_grant_type"})
+		}
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestConfig(t *testing.T, s *authServer) *Config {
+	// Fire polling timers immediately. The device code expiry never fires.
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		if d >= time.Minute {
+			return nil
+		}
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	t.Cleanup(func() { timeAfter = time.After })
+
+	srv := httptest.NewServer(s.handler(t))
+	t.Cleanup(srv.Close)
+
+	return &Config{
+		ClientID:      "tempest-cli",
+		DeviceAuthURL: srv.URL + "/oauth/device/code",
+		TokenURL:      srv.URL + "/oauth/token",
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	s := &authServer{approvedAfter: 2}
+	c := newTestConfig(t, s)
+
+	code, err := c.RequestDeviceCode(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-EFGH", code.UserCode)
+	assert.Equal(t, "https://example.com/device", code.VerificationURI)
+
+	token, err := c.PollToken(context.Background(), code)
+	require.NoError(t, err)
+	assert.Equal(t, 3, s.polls)
+	assert.Equal(t, "access_1", token.AccessToken)
+	assert.Equal(t, "refresh_1", token.RefreshToken)
+	assert.False(t, token.Expired())
+}
+
+func TestDeviceFlowDenied(t *testing.T) {
+	s := &authServer{deny: true}
+	c := newTestConfig(t, s)
+
+	code, err := c.RequestDeviceCode(context.Background())
+	require.NoError(t, err)
+
+	_, err = c.PollToken(context.Background(), code)
+	require.ErrorIs(t, err, ErrAccessDenied)
+}
+
+func TestDeviceFlowSlowDown(t *testing.T) {
+	var intervals []time.Duration
+	s := &authServer{slowDown: true}
+	c := newTestConfig(t, s)
+
+	next := timeAfter
+	timeAfter = func(d time.Duration) <-chan time.Time {
+		intervals = append(intervals, d)
+		return next(d)
+	}
+
+	code, err := c.RequestDeviceCode(context.Background())
+	require.NoError(t, err)
+
+	_, err = c.PollToken(context.Background(), code)
+	require.NoError(t, err)
+
+	// The first call is the expiry timer, followed by one wait per poll.
+	assert.Equal(t, []time.Duration{600 * time.Second, time.Second, 6 * time.Second}, intervals)
+}
+
+func TestRefresh(t *testing.T) {
+	s := &authServer{}
+	c := newTestConfig(t, s)
+
+	token, err := c.Refresh(context.Background(), "refresh_1")
+	require.NoError(t, err)
+	assert.Equal(t, "access_2", token.AccessToken)
+	// The server did not rotate the refresh token, so the old one is kept.
+	assert.Equal(t, "refresh_1", token.RefreshToken)
+
+	_, err = c.Refresh(context.Background(), "revoked")
+	require.ErrorContains(t, err, "refresh token revoked")
+}
+
+func TestEncodeDecodeToken(t *testing.T) {
+	token := &Token{
+		AccessToken:  "access_1",
+		RefreshToken: "refresh_1",
+		Expiry:       time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	s, err := token.Encode()
+	require.NoError(t, err)
+
+	decoded, ok := DecodeToken(s)
+	require.True(t, ok)
+	assert.Equal(t, token, decoded)
+
+	_, ok = DecodeToken("tk_plain_api_key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,114 @@
+package pemcheck
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Certificate returns an error unless s holds one or more PEM encoded X.509
+// certificates, such as a certificate chain.
+func Certificate(s string) error {
+	blocks, err := decode(s)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range blocks {
+		if b.Type != "CERTIFICATE" {
+			return fmt.Errorf("block %d: unexpected PEM type %q, want \"CERTIFICATE\"", i+1, b.Type)
+		}
+		if _, err := x509.ParseCertificate(b.Bytes); err != nil {
+			return fmt.Errorf("block %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// PrivateKey returns an error unless s holds a single PEM encoded private key,
+// in PKCS #8, PKCS #1 (RSA), SEC 1 (EC) or OpenSSH format.
+func PrivateKey(s string) error {
+	b, err := single(s)
+	if err != nil {
+		return err
+	}
+
+	switch b.Type {
+	case "PRIVATE KEY":
+		_, err = x509.ParsePKCS8PrivateKey(b.Bytes)
+	case "RSA PRIVATE KEY":
+		_, err = x509.ParsePKCS1PrivateKey(b.Bytes)
+	case "EC PRIVATE KEY":
+		_, err = x509.ParseECPrivateKey(b.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		// The OpenSSH format is not parsed further.
+	case "ENCRYPTED PRIVATE KEY":
+		return errors.New("encrypted private keys are not supported")
+	default:
+		return fmt.Errorf("unexpected PEM type %q. Accepted types: \"PRIVATE KEY\", \"RSA PRIVATE KEY\", \"EC PRIVATE KEY\", \"OPENSSH PRIVATE KEY\"", b.Type)
+	}
+
+	return err
+}
+
+// PublicKey returns an error unless s holds a single PEM encoded public key,
+// in PKIX or PKCS #1 (RSA) format.
+func PublicKey(s string) error {
+	b, err := single(s)
+	if err != nil {
+		return err
+	}
+
+	switch b.Type {
+	case "PUBLIC KEY":
+		_, err = x509.ParsePKIXPublicKey(b.Bytes)
+	case "RSA PUBLIC KEY":
+		_, err = x509.ParsePKCS1PublicKey(b.Bytes)
+	default:
+		return fmt.Errorf("unexpected PEM type %q. Accepted types: \"PUBLIC KEY\", \"RSA PUBLIC KEY\"", b.Type)
+	}
+
+	return err
+}
+
+func single(s string) (*pem.Block, error) {
+	blocks, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) > 1 {
+		return nil, fmt.Errorf("found %d PEM blocks, want 1", len(blocks))
+	}
+
+	return blocks[0], nil
+}
+
+// decode decodes all PEM blocks of s. Anything other than PEM blocks and
+// whitespace is rejected.
+func decode(s string) ([]*pem.Block, error) {
+	var blocks []*pem.Block
+
+	rest := []byte(s)
+	for {
+		var b *pem.Block
+		b, rest = pem.Decode(rest)
+		if b == nil {
+			break
+		}
+		blocks = append(blocks, b)
+	}
+
+	if len(blocks) == 0 {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if strings.TrimSpace(string(rest)) != "" {
+		return nil, errors.New("unexpected data after the PEM blocks")
+	}
+
+	return blocks, nil
+}
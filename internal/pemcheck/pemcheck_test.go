@@ -0,0 +1,57 @@
+package pemcheck_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/pemcheck"
+)
+
+func encode(typ string, b []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}))
+}
+
+func TestPEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certPEM := encode("CERTIFICATE", cert)
+	privateKeyPEM := encode("PRIVATE KEY", privateKey)
+	publicKeyPEM := encode("PUBLIC KEY", publicKey)
+
+	assert.NoError(t, pemcheck.Certificate(certPEM))
+	assert.NoError(t, pemcheck.Certificate(certPEM+certPEM), "certificate chains are accepted")
+	assert.NoError(t, pemcheck.PrivateKey(privateKeyPEM))
+	assert.NoError(t, pemcheck.PublicKey(publicKeyPEM))
+
+	assert.EqualError(t, pemcheck.Certificate("not pem"), "no PEM data found")
+	assert.EqualError(t, pemcheck.Certificate(privateKeyPEM), `block 1: unexpected PEM type "PRIVATE KEY", want "CERTIFICATE"`)
+	assert.EqualError(t, pemcheck.Certificate(certPEM+"trailing"), "unexpected data after the PEM blocks")
+	assert.Error(t, pemcheck.Certificate(encode("CERTIFICATE", []byte("garbage"))))
+	assert.EqualError(t, pemcheck.PrivateKey(privateKeyPEM+privateKeyPEM), "found 2 PEM blocks, want 1")
+	assert.ErrorContains(t, pemcheck.PrivateKey(publicKeyPEM), `unexpected PEM type "PUBLIC KEY"`)
+	assert.ErrorContains(t, pemcheck.PublicKey(certPEM), `unexpected PEM type "CERTIFICATE"`)
+}
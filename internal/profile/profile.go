@@ -0,0 +1,150 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultName is the profile used when no other profile is selected.
+	DefaultName = "default"
+
+	profilesYAMLName = "profiles.yaml"
+)
+
+var (
+	ErrNotFound = errors.New("profile not found")
+
+	nameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+type Config struct {
+	// The profile used when neither --profile nor TEMPEST_PROFILE are set.
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles,omitempty"`
+}
+
+type Profile struct {
+	// The Tempest API endpoint used by this profile.
+	APIEndpoint string `yaml:"api_endpoint,omitempty"`
+	// Where the token is stored: "auto", "keyring" or "file". Defaults to "auto".
+	TokenStore string `yaml:"token_store,omitempty"`
+	// An external command storing the token, following the git-credential
+	// protocol. Takes precedence over TokenStore.
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+	// The project ID the app commands send to the apps when neither
+	// --project-id nor a metadata file set one.
+	ProjectID string `yaml:"project_id,omitempty"`
+}
+
+// Dir returns the directory holding the user level Tempest configuration.
+// It honors XDG_CONFIG_HOME on Linux.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "tempest"), nil
+}
+
+// ValidateName returns an error if name cannot be used as a profile name.
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q. Must contain only letters, numbers, underscores, and dashes", name)
+	}
+
+	return nil
+}
+
+// Read reads the profiles file from the user configuration directory. A
+// missing file is not an error, an empty configuration is returned instead.
+func Read() (*Config, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Profiles: make(map[string]*Profile),
+	}
+
+	f, err := os.Open(filepath.Join(dir, profilesYAMLName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+
+	return cfg, nil
+}
+
+// Write writes the profiles file to the user configuration directory.
+func Write(cfg *Config) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, profilesYAMLName), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	encoder := yaml.NewEncoder(f)
+	encoder.SetIndent(2)
+
+	return encoder.Encode(cfg)
+}
+
+// Lookup returns the profile with the given name. The default profile always
+// exists, even when it has not been written to the profiles file.
+func (c *Config) Lookup(name string) (*Profile, error) {
+	if p, ok := c.Profiles[name]; ok {
+		return p, nil
+	}
+
+	if name == DefaultName {
+		return &Profile{}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Names returns the sorted names of all known profiles, including the
+// default profile.
+func (c *Config) Names() []string {
+	names := slices.Collect(maps.Keys(c.Profiles))
+	if !slices.Contains(names, DefaultName) {
+		names = append(names, DefaultName)
+	}
+	slices.Sort(names)
+
+	return names
+}
@@ -0,0 +1,60 @@
+package profile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/profile"
+)
+
+func TestReadMissingFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := profile.Read()
+	require.NoError(t, err)
+
+	assert.Empty(t, cfg.Current)
+	assert.Empty(t, cfg.Profiles)
+	assert.Equal(t, []string{profile.DefaultName}, cfg.Names())
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	err := profile.Write(&profile.Config{
+		Current: "staging",
+		Profiles: map[string]*profile.Profile{
+			"staging": {APIEndpoint: "https://staging.example.com/api/v1", ProjectID: "staging-project"},
+			"prod":    {},
+		},
+	})
+	require.NoError(t, err)
+
+	cfg, err := profile.Read()
+	require.NoError(t, err)
+
+	assert.Equal(t, "staging", cfg.Current)
+	assert.Equal(t, []string{"default", "prod", "staging"}, cfg.Names())
+
+	p, err := cfg.Lookup("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "https://staging.example.com/api/v1", p.APIEndpoint)
+	assert.Equal(t, "staging-project", p.ProjectID)
+
+	_, err = cfg.Lookup("missing")
+	require.ErrorIs(t, err, profile.ErrNotFound)
+
+	_, err = cfg.Lookup(profile.DefaultName)
+	require.NoError(t, err)
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"default", "staging", "prod-eu_1"} {
+		assert.NoError(t, profile.ValidateName(name), name)
+	}
+
+	for _, name := range []string{"", "-staging", "../etc", "a b", "a:b"} {
+		assert.Error(t, profile.ValidateName(name), name)
+	}
+}
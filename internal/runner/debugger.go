@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc/jsonrpc"
+	"os"
+	"syscall"
+	"time"
+)
+
+// stopDebugged stops the app run by the delve process p, listening on addr.
+// Interrupting delve would kill the app, so delve is detached from the app
+// first, and the app is then interrupted like one run without a debugger.
+func stopDebugged(p *os.Process, addr string) error {
+	pid, err := detachDebugger(addr)
+	if err != nil {
+		return errors.Join(fmt.Errorf("detach delve: %w", err), stop(p))
+	}
+
+	// Delve exits once detached.
+	return errors.Join(interrupt(pid), waitOrKill(p))
+}
+
+// detachDebugger detaches the delve server listening on addr from the app,
+// which keeps running, and returns the PID of the app.
+func detachDebugger(addr string) (int, error) {
+	conn, err := net.DialTimeout("tcp", addr, stopTimeout)
+	if err != nil {
+		return 0, err
+	}
+	client := jsonrpc.NewClient(conn)
+	defer func() { _ = client.Close() }()
+
+	var process struct{ Pid int }
+	if err := client.Call("RPCServer.ProcessPid", struct{}{}, &process); err != nil {
+		return 0, err
+	}
+
+	// Delve only detaches from a halted app, as its clients do on exit.
+	var halted struct{}
+	if err := client.Call("RPCServer.Command", struct{ Name string }{Name: "halt"}, &halted); err != nil {
+		return 0, err
+	}
+
+	var detached struct{}
+	if err := client.Call("RPCServer.Detach", struct{ Kill bool }{Kill: false}, &detached); err != nil {
+		return 0, err
+	}
+
+	return process.Pid, nil
+}
+
+// interrupt interrupts the process with the PID, which is not a child of the
+// runner, and kills it when it does not exit in time.
+func interrupt(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	if err := p.Signal(os.Interrupt); err != nil {
+		return p.Kill()
+	}
+
+	// The process cannot be waited for, so it is polled instead.
+	for deadline := time.Now().Add(stopTimeout); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if err := p.Signal(syscall.Signal(0)); err != nil {
+			return nil
+		}
+	}
+
+	return p.Kill()
+}
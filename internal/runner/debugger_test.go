@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"os/signal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess is not a test: it runs as the app or as delve when
+// started by the tests with RUNNER_TEST_PROCESS.
+func TestHelperProcess(t *testing.T) {
+	switch os.Getenv("RUNNER_TEST_PROCESS") {
+	case "app":
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt)
+		fmt.Println("ready")
+		<-c
+		fmt.Println("interrupted")
+		os.Exit(0)
+	case "delve":
+		select {}
+	}
+}
+
+func helperProcess(t *testing.T, kind string) *exec.Cmd {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "RUNNER_TEST_PROCESS="+kind)
+	return cmd
+}
+
+// DelveServer fakes the JSON-RPC API of a delve server debugging app, which
+// exits when detached.
+type DelveServer struct {
+	app    *os.Process
+	delve  *os.Process
+	halted bool
+	kill   bool
+}
+
+type ProcessPidOut struct{ Pid int }
+
+type CommandIn struct{ Name string }
+
+type DetachIn struct{ Kill bool }
+
+func (s *DelveServer) ProcessPid(_ struct{}, out *ProcessPidOut) error {
+	out.Pid = s.app.Pid
+	return nil
+}
+
+func (s *DelveServer) Command(in CommandIn, _ *struct{}) error {
+	s.halted = in.Name == "halt"
+	return nil
+}
+
+func (s *DelveServer) Detach(in DetachIn, _ *struct{}) error {
+	s.kill = in.Kill
+	return s.delve.Kill()
+}
+
+func TestStopDebugged(t *testing.T) {
+	app := helperProcess(t, "app")
+	stdout, err := app.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, app.Start())
+
+	lines := bufio.NewScanner(stdout)
+	require.True(t, lines.Scan())
+	require.Equal(t, "ready", lines.Text())
+
+	delve := helperProcess(t, "delve")
+	require.NoError(t, delve.Start())
+
+	srv := &DelveServer{app: app.Process, delve: delve.Process}
+	rpcServer := rpc.NewServer()
+	require.NoError(t, rpcServer.RegisterName("RPCServer", srv))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go rpcServer.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+
+	// The app is reaped as soon as it exits, as delve would.
+	exited := make(chan error, 1)
+	go func() {
+		for lines.Scan() {
+			if lines.Text() != "interrupted" {
+				continue
+			}
+			exited <- app.Wait()
+			return
+		}
+		exited <- fmt.Errorf("the app was not interrupted: %v", lines.Err())
+	}()
+
+	require.NoError(t, stopDebugged(delve.Process, l.Addr().String()))
+	require.NoError(t, <-exited)
+	assert.True(t, srv.halted)
+	assert.False(t, srv.kill)
+}
@@ -3,25 +3,114 @@ package runner
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/tempestdx/cli/internal/cassette"
 	"github.com/tempestdx/cli/internal/config"
 	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
 	appv1connect "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
 )
 
+// Option configures how an app is run.
+type Option func(*options)
+
+type options struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	httpClient *http.Client
+	cassette   *cassette.Options
+	coverDir   string
+	debugger   *Debugger
+}
+
+// Debugger configures the delve server an app is run under.
+type Debugger struct {
+	// Addr is the address the headless server listens on.
+	Addr string
+	// Continue runs the app at once, instead of waiting for a debugger to
+	// attach and continue it.
+	Continue bool
+}
+
+// stopTimeout is how long an app built by the runner has to stop once
+// interrupted, before it is killed.
+const stopTimeout = 10 * time.Second
+
+// WithOutput writes the lines the app logs to stdout and stderr to the given
+// writers, instead of printing them.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(o *options) {
+		o.stdout = stdout
+		o.stderr = stderr
+	}
+}
+
+// WithHTTPClient sends the requests of the client to the app with c, instead
+// of http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithCassette sends the outbound HTTP requests of the app through a proxy
+// recording them to, or replaying them from, a cassette. The app is built
+// before it is started, so that the go command does not use the proxy.
+func WithCassette(opts cassette.Options) Option {
+	return func(o *options) {
+		o.cassette = &opts
+	}
+}
+
+// WithCoverage builds the app with the coverage instrumentation of the
+// packages of the build directory, apps included, and writes its counters to
+// dir. The cancel function of the app interrupts it, so that the counters are
+// flushed, which is not supported on Windows.
+func WithCoverage(dir string) Option {
+	return func(o *options) {
+		o.coverDir = dir
+	}
+}
+
+// WithDebugger builds the app without optimizations, and runs it under a
+// headless delve server which debuggers attach to. The app is started once
+// it listens, which waits for a debugger unless d.Continue is set. The cancel
+// function of the app detaches delve from it before interrupting it.
+func WithDebugger(d Debugger) Option {
+	return func(o *options) {
+		o.debugger = &d
+	}
+}
+
+// LookDebugger returns the path of the dlv command.
+func LookDebugger() (string, error) {
+	path, err := exec.LookPath("dlv")
+	if err != nil {
+		return "", errors.New("dlv not found: install it with go install github.com/go-delve/delve/cmd/dlv@latest")
+	}
+
+	return path, nil
+}
+
 type Runner struct {
 	Client  appv1connect.AppServiceClient
 	Path    string
 	AppID   string
 	Version string
+	// Cassette is the proxy of the app when it was started WithCassette. The
+	// cancel function of the app closes it.
+	Cassette *cassette.Proxy
 }
 
 // Start the app runner for all apps and return clients for each service.
@@ -77,7 +166,7 @@ func StartApps(ctx context.Context, cfg *config.TempestConfig, cfgDir string) ([
 	var runners []Runner
 	for appID, versions := range cfg.Apps {
 		for _, version := range versions {
-			runner, err := createRunner(ctx, appID, version, port)
+			runner, err := createRunner(ctx, http.DefaultClient, appID, version, port)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -97,7 +186,12 @@ func StartApps(ctx context.Context, cfg *config.TempestConfig, cfgDir string) ([
 }
 
 // StartApp starts a single app runner and returns a client for the service.
-func StartApp(ctx context.Context, cfg *config.TempestConfig, cfgDir, appID string, appVersion *config.AppVersion) (Runner, func(), error) {
+func StartApp(ctx context.Context, cfg *config.TempestConfig, cfgDir, appID string, appVersion *config.AppVersion, opts ...Option) (Runner, func(), error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	absBuildDir := filepath.Join(cfgDir, cfg.BuildDir)
 
 	var cmd *exec.Cmd
@@ -105,65 +199,164 @@ func StartApp(ctx context.Context, cfg *config.TempestConfig, cfgDir, appID stri
 	if err != nil {
 		return Runner{}, nil, err
 	}
-	if info.IsDir() {
-		cmd = exec.Command("go", "run", ".")
+	if !info.IsDir() {
+		return Runner{}, nil, fmt.Errorf("invalid build directory: %s", absBuildDir)
+	}
+
+	// cleanup releases what was set up to run the app, besides its process.
+	var proxy *cassette.Proxy
+	cleanup := func() {}
+	built := o.cassette != nil || o.coverDir != "" || o.debugger != nil
+	if built {
+		var dlv string
+		var flags []string
+		if o.coverDir != "" {
+			flags = append(flags, "-cover")
+		}
+		if o.debugger != nil {
+			dlv, err = LookDebugger()
+			if err != nil {
+				return Runner{}, nil, err
+			}
+			flags = append(flags, "-gcflags=all=-N -l")
+		}
+
+		bin, removeBin, err := buildApp(absBuildDir, flags...)
+		if err != nil {
+			return Runner{}, nil, err
+		}
+		cleanup = removeBin
+
+		if o.debugger != nil {
+			args := []string{"exec", bin, "--headless", "--listen=" + o.debugger.Addr, "--api-version=2", "--accept-multiclient"}
+			if o.debugger.Continue {
+				args = append(args, "--continue")
+			}
+			cmd = exec.Command(dlv, args...)
+		} else {
+			cmd = exec.Command(bin)
+		}
 		cmd.Dir = absBuildDir
+		cmd.Env = os.Environ()
+
+		if o.cassette != nil {
+			proxy, err = cassette.Start(*o.cassette)
+			if err != nil {
+				removeBin()
+				return Runner{}, nil, err
+			}
+			cleanup = func() {
+				_ = proxy.Close()
+				removeBin()
+			}
+			cmd.Env = append(cmd.Env, proxy.Env()...)
+		}
+
+		if o.coverDir != "" {
+			cmd.Env = append(cmd.Env, "GOCOVERDIR="+o.coverDir)
+		}
 	} else {
-		return Runner{}, nil, fmt.Errorf("invalid build directory: %s", absBuildDir)
+		cmd = exec.Command("go", "run", ".")
+		cmd.Dir = absBuildDir
 	}
 
 	// Start process
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		cleanup()
 		return Runner{}, nil, err
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		cleanup()
 		return Runner{}, nil, err
 	}
 	err = cmd.Start()
 	if err != nil {
+		cleanup()
 		return Runner{}, nil, err
 	}
 
 	go func() {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
+			if o.stderr != nil {
+				fmt.Fprintln(o.stderr, scanner.Text())
+				continue
+			}
 			fmt.Println("App logged to stderr", "line", scanner.Text())
 		}
 	}()
 
+	logStdout := func(line string) {
+		if o.stdout != nil {
+			fmt.Fprintln(o.stdout, line)
+			return
+		}
+		fmt.Println("App logged to stdout", "line", line)
+	}
+
+	// The app prints its port first. Under a debugger, the lines of delve come
+	// before it.
+	var port string
 	scanner := bufio.NewScanner(stdout)
-	if !scanner.Scan() {
+	for port == "" && scanner.Scan() {
+		if line := scanner.Text(); o.debugger == nil || isPort(line) {
+			port = line
+		} else {
+			logStdout(line)
+		}
+	}
+	if port == "" {
+		_ = cmd.Process.Kill()
+		cleanup()
 		return Runner{}, nil, fmt.Errorf("scan: %w", scanner.Err())
 	}
 
-	port := scanner.Text()
-
 	go func() {
 		for scanner.Scan() {
-			fmt.Println("App logged to stdout", "line", scanner.Text())
+			logStdout(scanner.Text())
 		}
 	}()
 
-	runner, err := createRunner(ctx, appID, appVersion, port)
+	httpClient := http.DefaultClient
+	if o.httpClient != nil {
+		httpClient = o.httpClient
+	}
+
+	runner, err := createRunner(ctx, httpClient, appID, appVersion, port)
 	if err != nil {
+		_ = cmd.Process.Kill()
+		cleanup()
 		return Runner{}, nil, err
 	}
+	runner.Cassette = proxy
 
+	// The cancel function may be called again, once the app has stopped.
+	var once sync.Once
 	cancel := func() {
-		err = cmd.Process.Kill()
-		if err != nil {
-			fmt.Println("failed to kill app", "error", err)
-		}
+		once.Do(func() {
+			switch {
+			case o.debugger != nil:
+				err = stopDebugged(cmd.Process, o.debugger.Addr)
+			case built:
+				err = stop(cmd.Process)
+			default:
+				err = cmd.Process.Kill()
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "failed to kill app", "error", err)
+			}
+			cleanup()
+		})
 	}
 
 	return runner, cancel, nil
 }
 
-func createRunner(ctx context.Context, appID string, version *config.AppVersion, port string) (Runner, error) {
+func createRunner(ctx context.Context, httpClient *http.Client, appID string, version *config.AppVersion, port string) (Runner, error) {
 	path := appID + "-" + version.Version
-	client := appv1connect.NewAppServiceClient(http.DefaultClient, fmt.Sprintf("http://localhost:%s/%s", port, path))
+	client := appv1connect.NewAppServiceClient(httpClient, fmt.Sprintf("http://localhost:%s/%s", port, path))
 
 	// Confirm plugin is reachable.
 	err := backoff.Retry(func() error {
@@ -181,3 +374,56 @@ func createRunner(ctx context.Context, appID string, version *config.AppVersion,
 		Version: version.Version,
 	}, nil
 }
+
+func isPort(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 16)
+	return err == nil
+}
+
+// stop interrupts the app, and kills it when it does not stop in time, or
+// cannot be interrupted.
+func stop(p *os.Process) error {
+	if err := p.Signal(os.Interrupt); err != nil {
+		return p.Kill()
+	}
+
+	return waitOrKill(p)
+}
+
+// waitOrKill waits for the process to exit, and kills it when it does not
+// exit in time.
+func waitOrKill(p *os.Process) error {
+	done := make(chan struct{})
+	go func() {
+		_, _ = p.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout):
+		return p.Kill()
+	}
+}
+
+// buildApp builds the app in dir to a temporary binary with the go build
+// flags, and returns a function removing it.
+func buildApp(dir string, flags ...string) (string, func(), error) {
+	tmp, err := os.MkdirTemp("", "tempest-app-")
+	if err != nil {
+		return "", nil, err
+	}
+	remove := func() { _ = os.RemoveAll(tmp) }
+
+	bin := filepath.Join(tmp, "app")
+	args := append([]string{"build", "-o", bin}, flags...)
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("build app: %w\n%s", err, out)
+	}
+
+	return bin, remove, nil
+}
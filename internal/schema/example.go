@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Example returns an object matching the schema, with the required properties
+// only. Values are taken from const, default, enum and examples when declared,
+// and built from the type and its bounds otherwise. An error is returned when
+// the generated object does not match the schema, as formats and patterns are
+// not taken into account.
+func (s *Schema) Example() (map[string]any, error) {
+	v, ok := example(s.raw).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema is not an object schema")
+	}
+
+	if err := s.Validate(v); err != nil {
+		return nil, fmt.Errorf("generated example does not match the schema: %w", err)
+	}
+
+	return v, nil
+}
+
+func example(prop map[string]any) any {
+	if v, ok := prop["const"]; ok {
+		return v
+	}
+	if v, ok := prop["default"]; ok {
+		return v
+	}
+	if enum, ok := prop["enum"].([]any); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if examples, ok := prop["examples"].([]any); ok && len(examples) > 0 {
+		return examples[0]
+	}
+
+	switch typeName(prop) {
+	case "object":
+		properties, _ := prop["properties"].(map[string]any)
+		required := stringSlice(prop["required"])
+		slices.Sort(required)
+
+		obj := make(map[string]any, len(required))
+		for _, name := range required {
+			p, _ := properties[name].(map[string]any)
+			obj[name] = example(p)
+		}
+		return obj
+	case "array":
+		items, _ := prop["items"].(map[string]any)
+		minItems, _ := number(prop["minItems"])
+
+		a := make([]any, 0, int(minItems))
+		for range int(minItems) {
+			a = append(a, example(items))
+		}
+		return a
+	case "string":
+		return exampleString(prop)
+	case "integer":
+		return exampleNumber(prop, true)
+	case "number":
+		return exampleNumber(prop, false)
+	case "boolean":
+		return false
+	case "null":
+		return nil
+	}
+
+	// Without a single type, an empty string is the value most likely to be
+	// accepted.
+	return ""
+}
+
+func exampleString(prop map[string]any) string {
+	switch prop["format"] {
+	case "email":
+		return "test@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	}
+
+	s := "example"
+	if minLength, ok := number(prop["minLength"]); ok && len(s) < int(minLength) {
+		s += strings.Repeat("x", int(minLength)-len(s))
+	}
+	if maxLength, ok := number(prop["maxLength"]); ok && len(s) > int(maxLength) {
+		s = s[:int(maxLength)]
+	}
+
+	return s
+}
+
+func exampleNumber(prop map[string]any, integer bool) float64 {
+	n := 0.0
+	if minimum, ok := number(prop["minimum"]); ok {
+		n = minimum
+	} else if exclusiveMinimum, ok := number(prop["exclusiveMinimum"]); ok {
+		n = exclusiveMinimum + 1
+	} else if maximum, ok := number(prop["maximum"]); ok && maximum < 0 {
+		n = maximum
+	}
+
+	if integer {
+		n = math.Ceil(n)
+	}
+
+	return n
+}
+
+func number(v any) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
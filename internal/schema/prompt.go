@@ -0,0 +1,322 @@
+package schema
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Prompter interactively asks for a value matching a schema, one property at
+// a time.
+type Prompter struct {
+	In  *bufio.Reader
+	Out io.Writer
+}
+
+// NewPrompter returns a Prompter reading answers from in, and writing
+// questions to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{
+		In:  bufio.NewReader(in),
+		Out: out,
+	}
+}
+
+// Prompt asks for each property of the object schema, and returns the
+// resulting object. Required properties are asked first.
+func (p *Prompter) Prompt(s *Schema) (map[string]any, error) {
+	return p.object(s.raw, "")
+}
+
+func (p *Prompter) object(raw map[string]any, indent string) (map[string]any, error) {
+	properties, _ := raw["properties"].(map[string]any)
+	required := stringSlice(raw["required"])
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		ra, rb := slices.Contains(required, a), slices.Contains(required, b)
+		if ra != rb {
+			if ra {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	obj := make(map[string]any)
+	for _, name := range names {
+		prop, _ := properties[name].(map[string]any)
+
+		v, ok, err := p.property(name, prop, slices.Contains(required, name), indent)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			obj[name] = v
+		}
+	}
+
+	return obj, nil
+}
+
+// property asks for the value of a single property. It returns false when an
+// optional property was skipped.
+func (p *Prompter) property(name string, prop map[string]any, required bool, indent string) (any, bool, error) {
+	p.describe(name, prop, required, indent)
+
+	switch typeName(prop) {
+	case "object":
+		if !required {
+			set, err := p.confirm(indent+"  Set "+name+"?", false)
+			if err != nil || !set {
+				return nil, false, err
+			}
+		}
+		v, err := p.object(prop, indent+"  ")
+		return v, err == nil, err
+	case "array":
+		v, err := p.array(name, prop, required, indent)
+		if err != nil || (v == nil && !required) {
+			return nil, false, err
+		}
+		return v, true, nil
+	default:
+		return p.scalar(prop, required, indent)
+	}
+}
+
+func (p *Prompter) array(name string, prop map[string]any, required bool, indent string) ([]any, error) {
+	items, _ := prop["items"].(map[string]any)
+	var arr []any
+
+	for {
+		add, err := p.confirm(fmt.Sprintf("%s  Add an item to %s?", indent, name), required && len(arr) == 0)
+		if err != nil {
+			return nil, err
+		}
+		if !add {
+			break
+		}
+
+		title := fmt.Sprintf("%s[%d]", name, len(arr))
+		v, _, err := p.property(title, items, true, indent+"  ")
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+
+	if arr == nil && required {
+		return []any{}, nil
+	}
+
+	return arr, nil
+}
+
+// scalar asks for a string, number, integer, boolean or enum value, until
+// the answer matches the property schema.
+func (p *Prompter) scalar(prop map[string]any, required bool, indent string) (any, bool, error) {
+	// The property schema is compiled on its own, so references to the rest
+	// of the schema cannot be resolved. The whole value is validated later.
+	sch, _ := New(prop)
+	def, hasDefault := prop["default"]
+	enum, _ := prop["enum"].([]any)
+
+	for {
+		prompt := indent + "  > "
+		if hasDefault {
+			prompt = fmt.Sprintf("%s  [%s] > ", indent, formatValue(def))
+		}
+
+		answer, err := p.readLine(prompt)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if answer == "" {
+			if hasDefault {
+				return def, true, nil
+			}
+			if !required {
+				return nil, false, nil
+			}
+			fmt.Fprintf(p.Out, "%s  A value is required.\n", indent)
+			continue
+		}
+
+		v, err := parseAnswer(answer, prop, enum)
+		if err != nil {
+			fmt.Fprintf(p.Out, "%s  %s\n", indent, err)
+			continue
+		}
+
+		if sch != nil {
+			var ve *ValidationError
+			if err := sch.Validate(v); errors.As(err, &ve) {
+				for _, violation := range ve.Violations {
+					fmt.Fprintf(p.Out, "%s  %s\n", indent, violation.Message)
+				}
+				continue
+			}
+		}
+
+		return v, true, nil
+	}
+}
+
+// parseAnswer converts the answer to the type of the property.
+func parseAnswer(answer string, prop map[string]any, enum []any) (any, error) {
+	if len(enum) > 0 {
+		// Options can be chosen by number.
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(enum) {
+			return enum[i-1], nil
+		}
+		for _, e := range enum {
+			if formatValue(e) == answer {
+				return e, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not one of the options", answer)
+	}
+
+	switch typeName(prop) {
+	case "string":
+		return answer, nil
+	case "integer":
+		i, err := strconv.ParseInt(answer, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", answer)
+		}
+		return float64(i), nil
+	case "number":
+		f, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", answer)
+		}
+		return f, nil
+	case "boolean":
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true":
+			return true, nil
+		case "n", "no", "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean, answer yes or no", answer)
+	default:
+		// Without a single type, the answer is read as JSON, falling back
+		// to a string.
+		var v any
+		if err := json.Unmarshal([]byte(answer), &v); err != nil {
+			return answer, nil
+		}
+		return v, nil
+	}
+}
+
+// describe prints the name of the property, followed by its title,
+// description, examples and options.
+func (p *Prompter) describe(name string, prop map[string]any, required bool, indent string) {
+	header := indent + name
+	details := []string{}
+	if t := typeOf(prop); t != "" && prop["enum"] == nil {
+		details = append(details, t)
+	}
+	if required {
+		details = append(details, "required")
+	} else {
+		details = append(details, "optional")
+	}
+	header += " (" + strings.Join(details, ", ") + ")"
+
+	if title, _ := prop["title"].(string); title != "" && title != name {
+		header += ": " + title
+	}
+	fmt.Fprintln(p.Out, header)
+
+	if description, _ := prop["description"].(string); description != "" {
+		fmt.Fprintf(p.Out, "%s  %s\n", indent, description)
+	}
+
+	if examples, _ := prop["examples"].([]any); len(examples) > 0 {
+		values := make([]string, 0, len(examples))
+		for _, e := range examples {
+			values = append(values, formatValue(e))
+		}
+		fmt.Fprintf(p.Out, "%s  Examples: %s\n", indent, strings.Join(values, ", "))
+	}
+
+	if enum, _ := prop["enum"].([]any); len(enum) > 0 {
+		for i, e := range enum {
+			fmt.Fprintf(p.Out, "%s  %d) %s\n", indent, i+1, formatValue(e))
+		}
+	}
+}
+
+func (p *Prompter) confirm(question string, def bool) (bool, error) {
+	options := "y/N"
+	if def {
+		options = "Y/n"
+	}
+
+	for {
+		answer, err := p.readLine(fmt.Sprintf("%s (%s) ", question, options))
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+func (p *Prompter) readLine(prompt string) (string, error) {
+	fmt.Fprint(p.Out, prompt)
+
+	line, err := p.In.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// typeName returns the type of the property, or an empty string when the
+// property does not have a single type.
+func typeName(prop map[string]any) string {
+	if t, ok := prop["type"].(string); ok {
+		return t
+	}
+	if _, ok := prop["properties"]; ok {
+		return "object"
+	}
+
+	return ""
+}
+
+func formatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
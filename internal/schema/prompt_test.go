@@ -0,0 +1,70 @@
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/schema"
+)
+
+func TestPrompt(t *testing.T) {
+	sch := newSchema(t, `{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "title": "Name", "pattern": "^[a-z]+$", "examples": ["db"]},
+			"size": {"type": "integer", "default": 1},
+			"tier": {"enum": ["free", "paid"]},
+			"public": {"type": "boolean"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"owner": {
+				"type": "object",
+				"properties": {"email": {"type": "string"}},
+				"required": ["email"]
+			}
+		},
+		"required": ["name", "size"]
+	}`)
+
+	answers := strings.Join([]string{
+		// name: empty, then invalid, then valid.
+		"",
+		"Not Valid",
+		"db",
+		// size: not an integer, then the default.
+		"abc",
+		"",
+		// owner: set it, then its email.
+		"y",
+		"me@example.com",
+		// public: skipped.
+		"",
+		// tags: two items.
+		"y",
+		"a",
+		"y",
+		"b",
+		"n",
+		// tier: chosen by number.
+		"2",
+	}, "\n") + "\n"
+
+	var out strings.Builder
+	v, err := schema.NewPrompter(strings.NewReader(answers), &out).Prompt(sch)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"name":  "db",
+		"size":  float64(1),
+		"owner": map[string]any{"email": "me@example.com"},
+		"tags":  []any{"a", "b"},
+		"tier":  "paid",
+	}, v)
+
+	assert.Contains(t, out.String(), "name (string, required): Name\n  Examples: db\n")
+	assert.Contains(t, out.String(), "A value is required.")
+	assert.Contains(t, out.String(), `"abc" is not an integer`)
+	assert.Contains(t, out.String(), "does not match pattern")
+	assert.Contains(t, out.String(), "  1) free\n  2) paid\n")
+}
@@ -0,0 +1,249 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// resourceURL is the location the schema is registered at in the compiler.
+// It is only used to resolve references local to the schema.
+const resourceURL = "file:///schema.json"
+
+var printer = message.NewPrinter(language.English)
+
+// Schema is a JSON schema declared by an app, such as an input or properties
+// schema returned by Describe.
+type Schema struct {
+	raw      map[string]any
+	compiled *jsonschema.Schema
+}
+
+// New compiles the JSON schema raw. The $schema keyword is ignored and the
+// schema is compiled as draft 2020-12, so that compiling never requires the
+// Tempest meta schemas to be downloaded.
+func New(raw map[string]any) (*Schema, error) {
+	doc := maps.Clone(raw)
+	delete(doc, "$schema")
+	// Make the schema independent from the location it was published at.
+	delete(doc, "$id")
+
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+
+	if err := c.AddResource(resourceURL, doc); err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+
+	compiled, err := c.Compile(resourceURL)
+	if err != nil {
+		// Report where the schema breaks the meta schema, rather than the
+		// whole error tree.
+		var sve *jsonschema.SchemaValidationError
+		var ve *jsonschema.ValidationError
+		if errors.As(err, &sve) && errors.As(sve.Err, &ve) {
+			var violations []Violation
+			collectViolations(ve, &violations)
+			return nil, fmt.Errorf("not a valid draft 2020-12 JSON schema: %w", &ValidationError{Violations: violations})
+		}
+		return nil, fmt.Errorf("compile schema: %w", err)
+	}
+
+	return &Schema{
+		raw:      raw,
+		compiled: compiled,
+	}, nil
+}
+
+// Raw returns the schema as declared by the app.
+func (s *Schema) Raw() map[string]any {
+	return s.raw
+}
+
+// Violation is a single reason a value does not match a schema.
+type Violation struct {
+	// JSON pointer to the offending value. Empty for the root value.
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Pointer == "" {
+		return v.Message
+	}
+
+	return v.Pointer + ": " + v.Message
+}
+
+// ValidationError lists the violations found when validating a value.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	s := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		s = append(s, v.String())
+	}
+
+	return strings.Join(s, "; ")
+}
+
+// Validate validates v against the schema. When v does not match, the
+// returned error is a *ValidationError.
+func (s *Schema) Validate(v any) error {
+	err := s.compiled.Validate(v)
+	if err == nil {
+		return nil
+	}
+
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	var violations []Violation
+	collectViolations(ve, &violations)
+	slices.SortStableFunc(violations, func(a, b Violation) int {
+		return strings.Compare(a.Pointer, b.Pointer)
+	})
+
+	return &ValidationError{
+		Violations: slices.Compact(violations),
+	}
+}
+
+// collectViolations flattens the leaves of the error tree. Missing and
+// unexpected properties are reported on the property itself, rather than on
+// the parent object.
+func collectViolations(ve *jsonschema.ValidationError, violations *[]Violation) {
+	if len(ve.Causes) > 0 {
+		for _, c := range ve.Causes {
+			collectViolations(c, violations)
+		}
+		return
+	}
+
+	switch k := ve.ErrorKind.(type) {
+	case *kind.Required:
+		for _, p := range k.Missing {
+			*violations = append(*violations, Violation{
+				Pointer: pointer(slices.Concat(ve.InstanceLocation, []string{p})),
+				Message: "is required",
+			})
+		}
+	case *kind.AdditionalProperties:
+		for _, p := range k.Properties {
+			*violations = append(*violations, Violation{
+				Pointer: pointer(slices.Concat(ve.InstanceLocation, []string{p})),
+				Message: "is not allowed",
+			})
+		}
+	default:
+		*violations = append(*violations, Violation{
+			Pointer: pointer(ve.InstanceLocation),
+			Message: ve.ErrorKind.LocalizedString(printer),
+		})
+	}
+}
+
+// pointer returns the JSON pointer (RFC 6901) for the given location.
+func pointer(location []string) string {
+	var b strings.Builder
+	for _, tok := range location {
+		b.WriteByte('/')
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		b.WriteString(strings.ReplaceAll(tok, "/", "~1"))
+	}
+
+	return b.String()
+}
+
+// Field describes a top level property of an object schema.
+type Field struct {
+	Name        string
+	Type        string
+	Title       string
+	Description string
+	Required    bool
+}
+
+// Fields returns the top level properties of the schema, required fields
+// first, each group sorted by name.
+func (s *Schema) Fields() []Field {
+	properties, _ := s.raw["properties"].(map[string]any)
+	required := stringSlice(s.raw["required"])
+
+	fields := make([]Field, 0, len(properties))
+	for name, p := range properties {
+		p, _ := p.(map[string]any)
+		f := Field{
+			Name:     name,
+			Required: slices.Contains(required, name),
+		}
+		f.Type = typeOf(p)
+		f.Title, _ = p["title"].(string)
+		f.Description, _ = p["description"].(string)
+		fields = append(fields, f)
+	}
+
+	slices.SortFunc(fields, func(a, b Field) int {
+		if a.Required != b.Required {
+			if a.Required {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return fields
+}
+
+// typeOf returns a short description of the type of the property schema p.
+func typeOf(p map[string]any) string {
+	var t string
+	switch v := p["type"].(type) {
+	case string:
+		t = v
+	case []any:
+		t = strings.Join(stringSlice(v), "|")
+	}
+
+	if t == "array" {
+		if items, ok := p["items"].(map[string]any); ok {
+			if it := typeOf(items); it != "" {
+				t = "array of " + it
+			}
+		}
+	}
+
+	if enum, ok := p["enum"].([]any); ok {
+		values := make([]string, 0, len(enum))
+		for _, e := range enum {
+			values = append(values, fmt.Sprint(e))
+		}
+		t = "one of " + strings.Join(values, ", ")
+	}
+
+	return t
+}
+
+func stringSlice(v any) []string {
+	a, _ := v.([]any)
+	s := make([]string, 0, len(a))
+	for _, e := range a {
+		if e, ok := e.(string); ok {
+			s = append(s, e)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,108 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/schema"
+)
+
+const createSchema = `{
+	"$schema": "https://developer.tempestdx.com/schema/v1/tempest-app-schema.json",
+	"$id": "https://schema.tempestdx.io/privateapps/myapp/create.json",
+	"type": "object",
+	"properties": {
+		"name": {
+			"type": "string",
+			"description": "The name of the resource.",
+			"pattern": "^[a-z]+$"
+		},
+		"size": {"type": "integer", "minimum": 1},
+		"tier": {"enum": ["free", "paid"]},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"required": ["name", "size"],
+	"additionalProperties": false
+}`
+
+func newSchema(t *testing.T, s string) *schema.Schema {
+	t.Helper()
+
+	var raw map[string]any
+	require.NoError(t, json.Unmarshal([]byte(s), &raw))
+
+	sch, err := schema.New(raw)
+	require.NoError(t, err)
+
+	return sch
+}
+
+func TestValidate(t *testing.T) {
+	sch := newSchema(t, createSchema)
+
+	assert.NoError(t, sch.Validate(map[string]any{"name": "abc", "size": float64(2)}))
+
+	err := sch.Validate(map[string]any{
+		"name":  "ABC",
+		"tier":  "gold",
+		"color": "red",
+		"tags":  []any{"a", float64(1)},
+	})
+
+	var ve *schema.ValidationError
+	require.ErrorAs(t, err, &ve)
+
+	pointers := make([]string, 0, len(ve.Violations))
+	for _, v := range ve.Violations {
+		pointers = append(pointers, v.Pointer)
+	}
+	assert.Equal(t, []string{"/color", "/name", "/size", "/tags/1", "/tier"}, pointers)
+	assert.Equal(t, "/color: is not allowed", ve.Violations[0].String())
+	assert.Equal(t, "/size: is required", ve.Violations[2].String())
+}
+
+func TestFields(t *testing.T) {
+	sch := newSchema(t, createSchema)
+
+	assert.Equal(t, []schema.Field{
+		{Name: "name", Type: "string", Description: "The name of the resource.", Required: true},
+		{Name: "size", Type: "integer", Required: true},
+		{Name: "tags", Type: "array of string"},
+		{Name: "tier", Type: "one of free, paid"},
+	}, sch.Fields())
+}
+
+func TestExample(t *testing.T) {
+	v, err := newSchema(t, createSchema).Example()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "example", "size": float64(1)}, v)
+
+	v, err = newSchema(t, `{
+		"type": "object",
+		"properties": {
+			"region": {"type": "string", "default": "us-east-1"},
+			"tags": {"type": "array", "items": {"enum": ["a", "b"]}, "minItems": 2},
+			"owner": {"type": "string", "format": "email"}
+		},
+		"required": ["region", "tags", "owner"]
+	}`).Example()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"region": "us-east-1", "tags": []any{"a", "a"}, "owner": "test@example.com"}, v)
+
+	_, err = newSchema(t, `{
+		"type": "object",
+		"properties": {"id": {"type": "string", "pattern": "^[0-9]+$"}},
+		"required": ["id"]
+	}`).Example()
+	assert.EqualError(t, err, "generated example does not match the schema: /id: 'example' does not match pattern '^[0-9]+$'")
+}
+
+func TestNewInvalidSchema(t *testing.T) {
+	_, err := schema.New(map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"size": map[string]any{"minimum": "1"}},
+	})
+	assert.EqualError(t, err, "not a valid draft 2020-12 JSON schema: /properties/size/minimum: got string, want number")
+}
@@ -0,0 +1,60 @@
+package secret
+
+import "errors"
+
+// Fallback uses Primary and falls back to Secondary when Primary is not
+// available, for example when there is no keyring on a headless machine.
+type Fallback struct {
+	Primary   TokenStore
+	Secondary TokenStore
+}
+
+var _ TokenStore = (*Fallback)(nil)
+
+func (f *Fallback) Set(token string) error {
+	err := f.Primary.Set(token)
+	if err == nil {
+		return nil
+	}
+
+	if fbErr := f.Secondary.Set(token); fbErr != nil {
+		return errors.Join(err, fbErr)
+	}
+
+	return nil
+}
+
+func (f *Fallback) Get() (string, error) {
+	token, err := f.Primary.Get()
+	if err == nil {
+		return token, nil
+	}
+
+	token, fbErr := f.Secondary.Get()
+	if fbErr == nil {
+		return token, nil
+	}
+
+	// When either store simply has no token, the other store's error is the
+	// more useful one to report.
+	switch {
+	case errors.Is(fbErr, ErrNotFound):
+		return "", err
+	case errors.Is(err, ErrNotFound):
+		return "", fbErr
+	}
+
+	return "", errors.Join(err, fbErr)
+}
+
+// Delete removes the token from both stores. A store that cannot be reached
+// holds no token, so an error is only returned if both stores fail.
+func (f *Fallback) Delete() error {
+	err := f.Primary.Delete()
+	fbErr := f.Secondary.Delete()
+	if err != nil && fbErr != nil {
+		return errors.Join(err, fbErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package secret_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/secret"
+)
+
+var errUnavailable = errors.New("keyring unavailable")
+
+// memoryStore is an in memory TokenStore. When unavailable is set, every
+// operation fails as a keyring without a secret service would.
+type memoryStore struct {
+	token       string
+	unavailable bool
+}
+
+func (m *memoryStore) Set(token string) error {
+	if m.unavailable {
+		return errUnavailable
+	}
+	m.token = token
+	return nil
+}
+
+func (m *memoryStore) Get() (string, error) {
+	if m.unavailable {
+		return "", errUnavailable
+	}
+	if m.token == "" {
+		return "", secret.ErrNotFound
+	}
+	return m.token, nil
+}
+
+func (m *memoryStore) Delete() error {
+	if m.unavailable {
+		return errUnavailable
+	}
+	m.token = ""
+	return nil
+}
+
+func TestFallbackPrimaryAvailable(t *testing.T) {
+	primary, secondary := &memoryStore{}, &memoryStore{}
+	f := &secret.Fallback{Primary: primary, Secondary: secondary}
+
+	require.NoError(t, f.Set("tok_123"))
+	assert.Equal(t, "tok_123", primary.token)
+	assert.Empty(t, secondary.token)
+
+	token, err := f.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+}
+
+func TestFallbackPrimaryUnavailable(t *testing.T) {
+	primary, secondary := &memoryStore{unavailable: true}, &memoryStore{}
+	f := &secret.Fallback{Primary: primary, Secondary: secondary}
+
+	require.NoError(t, f.Set("tok_123"))
+	assert.Equal(t, "tok_123", secondary.token)
+
+	token, err := f.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+
+	require.NoError(t, f.Delete())
+	assert.Empty(t, secondary.token)
+}
+
+func TestFallbackBothUnavailable(t *testing.T) {
+	f := &secret.Fallback{
+		Primary:   &memoryStore{unavailable: true},
+		Secondary: &memoryStore{unavailable: true},
+	}
+
+	require.ErrorIs(t, f.Set("tok_123"), errUnavailable)
+	require.ErrorIs(t, f.Delete(), errUnavailable)
+
+	_, err := f.Get()
+	require.ErrorIs(t, err, errUnavailable)
+}
+
+func TestFallbackGetError(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   *memoryStore
+		secondary *memoryStore
+	}{
+		{name: "primary unavailable", primary: &memoryStore{unavailable: true}, secondary: &memoryStore{}},
+		{name: "secondary unavailable", primary: &memoryStore{}, secondary: &memoryStore{unavailable: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &secret.Fallback{Primary: tt.primary, Secondary: tt.secondary}
+
+			_, err := f.Get()
+			require.ErrorIs(t, err, errUnavailable)
+			assert.NotErrorIs(t, err, secret.ErrNotFound)
+		})
+	}
+
+	_, err := (&secret.Fallback{Primary: &memoryStore{}, Secondary: &memoryStore{}}).Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+}
@@ -0,0 +1,204 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	encryptedPrefix = "tempest-token:v1:"
+
+	saltSize   = 16
+	kdfIter    = 600_000
+	kdfKeySize = 32
+)
+
+var (
+	ErrPassphraseRequired = errors.New("token is encrypted and no passphrase was provided")
+	ErrWrongPassphrase    = errors.New("decrypt token: wrong passphrase or corrupted file")
+)
+
+// File stores the token in a file readable only by the current user. The token
+// is encrypted with a key derived from Passphrase when one is set.
+type File struct {
+	// Path to the token file. Parent directories are created with 0700.
+	Path string
+	// Passphrase used to encrypt the token on Set. When empty, the token is
+	// stored in plaintext.
+	Passphrase string
+	// PromptPassphrase is called when an encrypted token is read and
+	// Passphrase is empty. The passphrase is then kept in Passphrase. Optional.
+	PromptPassphrase func() (string, error)
+}
+
+var _ TokenStore = (*File)(nil)
+
+func (f *File) Set(token string) error {
+	data := token
+	if f.Passphrase != "" {
+		var err error
+		data, err = encrypt(token, f.Passphrase)
+		if err != nil {
+			return err
+		}
+	}
+
+	dir := filepath.Dir(f.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create token directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never leaves a
+	// truncated token behind.
+	tmp, err := os.CreateTemp(dir, ".token-*")
+	if err != nil {
+		return fmt.Errorf("create token file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(0o600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("chmod token file: %w", err)
+	}
+
+	if _, err := tmp.WriteString(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write token file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close token file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f *File) Get() (string, error) {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	data := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(data, encryptedPrefix) {
+		return data, nil
+	}
+
+	passphrase := f.Passphrase
+	if passphrase == "" && f.PromptPassphrase != nil {
+		passphrase, err = f.PromptPassphrase()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if passphrase == "" {
+		return "", ErrPassphraseRequired
+	}
+
+	token, err := decrypt(data, passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep the passphrase, so a token stored later is encrypted as well.
+	f.Passphrase = passphrase
+
+	return token, nil
+}
+
+func (f *File) Delete() error {
+	err := os.Remove(f.Path)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Encrypted reports whether the stored token is encrypted.
+func (f *File) Encrypted() bool {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(string(b), encryptedPrefix)
+}
+
+func encrypt(token, passphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	// Layout: salt | nonce | ciphertext.
+	out := append(salt, nonce...)
+	out = gcm.Seal(out, nonce, []byte(token), nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+func decrypt(data, passphrase string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(data, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("decode token: %w", err)
+	}
+
+	if len(raw) < saltSize {
+		return "", ErrWrongPassphrase
+	}
+
+	gcm, err := newGCM(passphrase, raw[:saltSize])
+	if err != nil {
+		return "", err
+	}
+
+	raw = raw[saltSize:]
+	if len(raw) < gcm.NonceSize() {
+		return "", ErrWrongPassphrase
+	}
+
+	token, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+
+	return string(token), nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	k, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIter, kdfKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
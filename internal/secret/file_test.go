@@ -0,0 +1,70 @@
+package secret_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/secret"
+)
+
+func TestFilePlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens", "default")
+	f := &secret.File{Path: path}
+
+	_, err := f.Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	require.NoError(t, f.Set("tok_123"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	info, err = os.Stat(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	token, err := f.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+	assert.False(t, f.Encrypted())
+
+	require.NoError(t, f.Delete())
+	require.NoError(t, f.Delete())
+
+	_, err = f.Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+}
+
+func TestFileEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default")
+
+	require.NoError(t, (&secret.File{Path: path, Passphrase: "correct horse"}).Set("tok_123"))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "tok_123")
+
+	token, err := (&secret.File{Path: path, Passphrase: "correct horse"}).Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+
+	_, err = (&secret.File{Path: path, Passphrase: "battery staple"}).Get()
+	require.ErrorIs(t, err, secret.ErrWrongPassphrase)
+
+	_, err = (&secret.File{Path: path}).Get()
+	require.ErrorIs(t, err, secret.ErrPassphraseRequired)
+
+	prompted := &secret.File{
+		Path:             path,
+		PromptPassphrase: func() (string, error) { return "correct horse", nil },
+	}
+	assert.True(t, prompted.Encrypted())
+
+	token, err = prompted.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+}
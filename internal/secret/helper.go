@@ -0,0 +1,170 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const helperTimeout = 30 * time.Second
+
+// Helper stores the token with an external credential helper, such as a
+// wrapper around Vault or 1Password.
+//
+// The helper follows the git-credential protocol. Command is run by the shell
+// with the action (get, store or erase) appended as the last argument, and
+// receives key=value lines on stdin, terminated by a blank line:
+//
+//	protocol=https
+//	host=developer.tempestdx.com
+//	username=default
+//	password=<token>   (store only)
+//
+// On get, the helper prints the token as a password=<token> line. Printing
+// nothing means no token is stored.
+//
+// Results are cached in memory for the lifetime of the Helper, or until
+// Refresh is called.
+type Helper struct {
+	// The helper command line.
+	Command string
+	// The host of the Tempest API endpoint.
+	Host string
+	// The profile name, sent as the username.
+	Username string
+
+	mu     sync.Mutex
+	cached *string
+}
+
+var _ TokenStore = (*Helper)(nil)
+
+func (h *Helper) Set(token string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.run("store", map[string]string{"password": token}); err != nil {
+		return err
+	}
+
+	h.cached = &token
+
+	return nil
+}
+
+func (h *Helper) Get() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil {
+		if *h.cached == "" {
+			return "", ErrNotFound
+		}
+		return *h.cached, nil
+	}
+
+	out, err := h.run("get", nil)
+	if err != nil {
+		return "", err
+	}
+
+	token := out["password"]
+	h.cached = &token
+
+	if token == "" {
+		return "", ErrNotFound
+	}
+
+	return token, nil
+}
+
+// Refresh drops the cached result, so that the next Get runs the helper
+// again and picks up a rotated token.
+func (h *Helper) Refresh() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cached = nil
+}
+
+func (h *Helper) Delete() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.run("erase", nil); err != nil {
+		return err
+	}
+
+	empty := ""
+	h.cached = &empty
+
+	return nil
+}
+
+func (h *Helper) run(action string, attrs map[string]string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), helperTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Command+" "+action)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Command+" "+action)
+	}
+
+	var stdin bytes.Buffer
+	fmt.Fprintf(&stdin, "protocol=https\nhost=%s\n", h.Host)
+	if h.Username != "" {
+		fmt.Fprintf(&stdin, "username=%s\n", h.Username)
+	}
+	for k, v := range attrs {
+		fmt.Fprintf(&stdin, "%s=%s\n", k, v)
+	}
+	stdin.WriteString("\n")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", helperTimeout)
+		}
+
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return nil, fmt.Errorf("credential helper %q failed to %s the token: %w: %s", h.Command, action, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %q failed to %s the token: %w", h.Command, action, err)
+	}
+
+	return parseHelperOutput(&stdout)
+}
+
+func parseHelperOutput(out *bytes.Buffer) (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("credential helper returned an invalid line %q, expected key=value", line)
+		}
+		attrs[k] = v
+	}
+
+	return attrs, scanner.Err()
+}
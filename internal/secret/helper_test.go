@@ -0,0 +1,117 @@
+package secret_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/secret"
+)
+
+// helperScript is a credential helper keeping the token in a file next to it,
+// and logging every call.
+const helperScript = `#!/bin/sh
+dir=$(dirname "$0")
+input=$(cat)
+echo "$1" >> "$dir/calls"
+case "$1" in
+get)
+	if [ -f "$dir/token" ]; then
+		echo "password=$(cat "$dir/token")"
+	fi
+	;;
+store)
+	echo "$input" | grep '^host=developer.tempestdx.com$' > /dev/null || exit 1
+	echo "$input" | sed -n 's/^password=//p' > "$dir/token"
+	;;
+erase)
+	rm -f "$dir/token"
+	;;
+esac
+`
+
+func newTestHelper(t *testing.T) (*secret.Helper, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "helper.sh")
+	require.NoError(t, os.WriteFile(script, []byte(helperScript), 0o700))
+
+	return &secret.Helper{
+		Command:  script,
+		Host:     "developer.tempestdx.com",
+		Username: "default",
+	}, dir
+}
+
+func TestHelper(t *testing.T) {
+	h, dir := newTestHelper(t)
+
+	_, err := h.Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	require.NoError(t, h.Set("tok_123"))
+
+	token, err := (&secret.Helper{Command: h.Command, Host: h.Host}).Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+
+	require.NoError(t, h.Delete())
+
+	_, err = h.Get()
+	require.ErrorIs(t, err, secret.ErrNotFound)
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	require.NoError(t, err)
+	// The last get is served from the cache.
+	assert.Equal(t, "get\nstore\nget\nerase\n", string(calls))
+}
+
+func TestHelperCachesGet(t *testing.T) {
+	h, dir := newTestHelper(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("tok_123"), 0o600))
+
+	for range 3 {
+		token, err := h.Get()
+		require.NoError(t, err)
+		assert.Equal(t, "tok_123", token)
+	}
+
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	require.NoError(t, err)
+	assert.Equal(t, "get\n", string(calls))
+}
+
+func TestHelperRefresh(t *testing.T) {
+	h, dir := newTestHelper(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("tok_123"), 0o600))
+
+	token, err := h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+
+	// The token is rotated behind the helper.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "token"), []byte("tok_456"), 0o600))
+	token, err = h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_123", token)
+
+	h.Refresh()
+	token, err = h.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "tok_456", token)
+}
+
+func TestHelperError(t *testing.T) {
+	h := &secret.Helper{Command: "echo 'vault is sealed' >&2; exit 3;", Host: "developer.tempestdx.com"}
+
+	_, err := h.Get()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get the token")
+	assert.Contains(t, err.Error(), "vault is sealed")
+}
@@ -6,23 +6,40 @@ import (
 	"github.com/zalando/go-keyring"
 )
 
-type Keyring struct{}
+type Keyring struct {
+	// Profile namespaces the token in the keyring. The empty profile uses the
+	// original, unnamespaced entry.
+	Profile string
+}
 
 var _ TokenStore = (*Keyring)(nil)
 
-func (*Keyring) Set(token string) error {
-	return keyring.Set(service, key, token)
+func (k *Keyring) Set(token string) error {
+	return keyring.Set(service, k.key(), token)
 }
 
-func (*Keyring) Get() (string, error) {
-	return keyring.Get(service, key)
+func (k *Keyring) Get() (string, error) {
+	token, err := keyring.Get(service, k.key())
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+
+	return token, err
 }
 
-func (*Keyring) Delete() error {
-	err := keyring.Delete(service, key)
+func (k *Keyring) Delete() error {
+	err := keyring.Delete(service, k.key())
 	if err != nil && errors.Is(err, keyring.ErrNotFound) {
 		return nil
 	}
 
 	return err
 }
+
+func (k *Keyring) key() string {
+	if k.Profile == "" {
+		return key
+	}
+
+	return key + ":" + k.Profile
+}
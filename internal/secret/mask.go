@@ -0,0 +1,18 @@
+package secret
+
+import "strings"
+
+// Mask hides all but the first and last four characters of a token, so it
+// can be identified without being revealed. Short tokens are hidden entirely.
+func Mask(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	const visible = 4
+	if len(token) <= 3*visible {
+		return strings.Repeat("*", len(token))
+	}
+
+	return token[:visible] + strings.Repeat("*", len(token)-2*visible) + token[len(token)-visible:]
+}
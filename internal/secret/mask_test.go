@@ -0,0 +1,28 @@
+package secret_test
+
+import (
+	"testing"
+
+	"github.com/tempestdx/cli/internal/secret"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected string
+	}{
+		{token: "", expected: ""},
+		{token: "short", expected: "*****"},
+		{token: "exactly12chr", expected: "************"},
+		{token: "tk_1234567890abcd", expected: "tk_1*********abcd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got := secret.Mask(tt.token)
+			if got != tt.expected {
+				t.Errorf("Mask(%q) = %q, want %q", tt.token, got, tt.expected)
+			}
+		})
+	}
+}
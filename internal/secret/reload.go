@@ -0,0 +1,125 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reasons passed to Reloader.Load and Reloader.OnReload.
+const (
+	ReloadReasonFileChanged  = "file_changed"
+	ReloadReasonInterval     = "interval"
+	ReloadReasonUnauthorized = "unauthorized"
+)
+
+const defaultFileCheckInterval = 2 * time.Second
+
+// Reloader keeps the token of a Transport up to date when it is rotated. The
+// token is reloaded when File changes, every Interval, and when the API
+// rejects the token. Use Refresh as the RefreshFunc of the Transport.
+type Reloader struct {
+	// Transport whose token is updated.
+	Transport *Transport
+	// Load returns the current token. Calls are serialized.
+	Load func(ctx context.Context, reason string) (string, error)
+	// File holding the token, checked for changes. Optional.
+	File string
+	// How often File is checked for changes. Defaults to 2s.
+	FileCheckInterval time.Duration
+	// How often the token is reloaded regardless of changes. Optional.
+	Interval time.Duration
+	// Called after every reload attempt. Optional.
+	OnReload func(reason string, changed bool, err error)
+
+	loadMu sync.Mutex
+}
+
+// Run reloads the token until ctx is done.
+func (r *Reloader) Run(ctx context.Context) {
+	var interval <-chan time.Time
+	if r.Interval > 0 {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		interval = ticker.C
+	}
+
+	var fileCheck <-chan time.Time
+	var lastMod fileVersion
+	if r.File != "" {
+		checkInterval := r.FileCheckInterval
+		if checkInterval <= 0 {
+			checkInterval = defaultFileCheckInterval
+		}
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		fileCheck = ticker.C
+		lastMod = statFile(r.File)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-interval:
+			r.reload(ctx, ReloadReasonInterval)
+		case <-fileCheck:
+			mod := statFile(r.File)
+			if mod == lastMod {
+				continue
+			}
+			lastMod = mod
+			r.reload(ctx, ReloadReasonFileChanged)
+		}
+	}
+}
+
+// Refresh loads the token after the API rejected it.
+func (r *Reloader) Refresh(ctx context.Context) (string, error) {
+	token, err := r.load(ctx, ReloadReasonUnauthorized)
+	if r.OnReload != nil {
+		r.OnReload(ReloadReasonUnauthorized, err == nil && token != r.Transport.currentToken(), err)
+	}
+
+	return token, err
+}
+
+func (r *Reloader) load(ctx context.Context, reason string) (string, error) {
+	r.loadMu.Lock()
+	defer r.loadMu.Unlock()
+
+	return r.Load(ctx, reason)
+}
+
+func (r *Reloader) reload(ctx context.Context, reason string) {
+	token, err := r.load(ctx, reason)
+	changed := false
+	if err == nil {
+		changed = r.Transport.SetToken(token)
+	}
+
+	if r.OnReload != nil {
+		r.OnReload(reason, changed, err)
+	}
+}
+
+// fileVersion identifies a version of a file. Secret managers often replace
+// the file, so both the modification time and the size are compared.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+
+	return fileVersion{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
@@ -0,0 +1,113 @@
+package secret_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/secret"
+)
+
+type reloadEvent struct {
+	reason  string
+	changed bool
+}
+
+// newFileReloader returns a transport using the token in a file, and a
+// reloader reading the file on every reload.
+func newFileReloader(t *testing.T) (*secret.Transport, *secret.Reloader, string, func() []reloadEvent) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+
+	var mu sync.Mutex
+	var events []reloadEvent
+
+	r := &secret.Reloader{
+		Load: func(context.Context, string) (string, error) {
+			b, err := os.ReadFile(path)
+			return strings.TrimSpace(string(b)), err
+		},
+		File:              path,
+		FileCheckInterval: 10 * time.Millisecond,
+		OnReload: func(reason string, changed bool, err error) {
+			assert.NoError(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, reloadEvent{reason, changed})
+		},
+	}
+	r.Transport = secret.NewRefreshingTransport("old", r.Refresh)
+
+	return r.Transport, r, path, func() []reloadEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]reloadEvent(nil), events...)
+	}
+}
+
+func TestReloaderReloadsOnUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport, _, path, events := newFileReloader(t)
+	client := &http.Client{Transport: transport}
+
+	// The token has not been rotated yet, so the request is not retried.
+	res, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	require.NoError(t, os.WriteFile(path, []byte("new"), 0o600))
+
+	res, err = client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Equal(t, []reloadEvent{
+		{secret.ReloadReasonUnauthorized, false},
+		{secret.ReloadReasonUnauthorized, true},
+	}, events())
+}
+
+func TestReloaderReloadsOnFileChange(t *testing.T) {
+	transport, r, path, events := newFileReloader(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx)
+	}()
+	// Stop the reloader before the temporary directory is removed.
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Let the reloader record the initial version of the file.
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte("rotated"), 0o600))
+
+	require.Eventually(t, func() bool {
+		return len(events()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, reloadEvent{secret.ReloadReasonFileChanged, true}, events()[0])
+	assert.False(t, transport.SetToken("rotated"), "the transport should already use the rotated token")
+}
@@ -1,10 +1,15 @@
 package secret
 
+import "errors"
+
 const (
 	service = "tempest_cli"
 	key     = "api_token"
 )
 
+// ErrNotFound is returned by a TokenStore when no token has been stored.
+var ErrNotFound = errors.New("token not found")
+
 type TokenStore interface {
 	// Set a secret in the store.
 	Set(secret string) error
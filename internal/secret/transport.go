@@ -1,22 +1,128 @@
 package secret
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 )
 
+// RefreshFunc returns a new token after the current one was rejected.
+type RefreshFunc func(ctx context.Context) (string, error)
+
 type Transport struct {
 	RoundTripper http.RoundTripper
-	token        string
+
+	mu    sync.Mutex
+	token string
+
+	// refreshMu serializes refreshes, without blocking requests.
+	refreshMu sync.Mutex
+	refresh   RefreshFunc
 }
 
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req.Header.Set("Authorization", "Bearer "+t.token)
+	token := t.currentToken()
+
+	res, err := t.roundTrip(req, token)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || t.refresh == nil {
+		return res, err
+	}
+
+	// The request body has been consumed, so it can only be retried when it
+	// can be recreated.
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+
+	newToken, err := t.refreshToken(req.Context(), token)
+	if err != nil {
+		return res, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return res, nil
+		}
+	}
+
+	_ = res.Body.Close()
+
+	return t.roundTrip(retry, newToken)
+}
+
+func (t *Transport) roundTrip(req *http.Request, token string) (*http.Response, error) {
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
 	return t.RoundTripper.RoundTrip(req)
 }
 
+func (t *Transport) currentToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.token
+}
+
+// refreshToken refreshes the token once, even when several requests are
+// rejected concurrently. rejected is the token the server rejected.
+func (t *Transport) refreshToken(ctx context.Context, rejected string) (string, error) {
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+
+	// Another request already refreshed the token.
+	if current := t.currentToken(); current != rejected {
+		return current, nil
+	}
+
+	token, err := t.refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("refresh token: %w", err)
+	}
+
+	// Retrying with the rejected token would fail again.
+	if token == rejected {
+		return "", errors.New("refresh token: token unchanged")
+	}
+
+	t.SetToken(token)
+
+	return token, nil
+}
+
+// SetToken replaces the token used for new requests, and reports whether it
+// changed.
+func (t *Transport) SetToken(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == token {
+		return false
+	}
+
+	t.token = token
+
+	return true
+}
+
 func NewTransportWithToken(token string) *Transport {
 	return &Transport{
 		RoundTripper: http.DefaultTransport,
 		token:        token,
 	}
 }
+
+// NewRefreshingTransport returns a Transport that calls refresh to get a new
+// token when a request is rejected with 401 Unauthorized, and retries the
+// request once with the new token.
+func NewRefreshingTransport(token string, refresh RefreshFunc) *Transport {
+	return &Transport{
+		RoundTripper: http.DefaultTransport,
+		token:        token,
+		refresh:      refresh,
+	}
+}
@@ -0,0 +1,83 @@
+package shell
+
+import (
+	"slices"
+	"strings"
+)
+
+// Completer completes the words of a line.
+type Completer struct {
+	// Commands are the candidates of the first word.
+	Commands []string
+	// Args returns the candidates of the argument following args, the
+	// previous arguments of the command.
+	Args func(command string, args []string) []string
+	// Vars returns the names of the variables, the candidates of words
+	// starting with "${".
+	Vars func() []string
+}
+
+// Complete completes the word before the byte position pos of line. It returns
+// the new line and position, and the candidates matching the word when there
+// are several of them.
+func (c *Completer) Complete(line string, pos int) (string, int, []string) {
+	before, after := line[:pos], line[pos:]
+	start := strings.LastIndexAny(before, " \t") + 1
+	word := before[start:]
+
+	var candidates []string
+	suffix := " "
+	if strings.HasPrefix(word, "${") {
+		if c.Vars != nil {
+			for _, v := range c.Vars() {
+				candidates = append(candidates, "${"+v+"}")
+			}
+		}
+		suffix = ""
+	} else {
+		previous := strings.Fields(before[:start])
+		// The variable of an assignment is not a word of the command.
+		if len(previous) >= 2 && previous[1] == "=" {
+			previous = previous[2:]
+		} else if len(previous) >= 1 && strings.HasSuffix(previous[0], "=") {
+			previous = previous[1:]
+		}
+
+		switch {
+		case len(previous) == 0:
+			candidates = c.Commands
+		case c.Args != nil:
+			candidates = c.Args(previous[0], previous[1:])
+		}
+	}
+
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, word) && !slices.Contains(matches, cand) {
+			matches = append(matches, cand)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return line, pos, nil
+	case 1:
+		completed := before[:start] + matches[0] + suffix
+		return completed + after, len(completed), nil
+	}
+
+	slices.Sort(matches)
+	completed := before[:start] + commonPrefix(matches)
+	return completed + after, len(completed), matches
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
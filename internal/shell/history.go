@@ -0,0 +1,93 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HistorySize is the number of lines kept in the history.
+const HistorySize = 1000
+
+// History is the history of the lines of the shell, saved to a file so that
+// it is available to the next sessions. It implements the History of
+// golang.org/x/term.
+type History struct {
+	path    string
+	entries []string
+}
+
+// LoadHistory reads the history file at path. A missing file is an empty
+// history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(h.entries) > HistorySize {
+		h.entries = h.entries[len(h.entries)-HistorySize:]
+		// The file only grows when lines are added, it is trimmed here.
+		if err := os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// Add adds a line to the history, unless it is blank or repeats the last one,
+// and appends it to the history file. Failing to write the file only loses the
+// line for the next sessions.
+func (h *History) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > HistorySize {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	_, _ = f.WriteString(entry + "\n")
+}
+
+// Len returns the number of lines in the history.
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// At returns a line of the history, 0 being the most recent one.
+func (h *History) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
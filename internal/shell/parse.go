@@ -0,0 +1,151 @@
+// Package shell implements the line syntax, variables, completion and history
+// of the interactive app shell.
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Line is a parsed line of the shell.
+type Line struct {
+	// Var is the variable the result of the command is assigned to, set by
+	// lines such as `db = create database {"name": "a"}`.
+	Var string
+	// Name of the command, empty for blank lines.
+	Name string
+	Args []string
+}
+
+var assignRegex = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*=\s*`)
+
+// Parse parses a line of the shell. See Split for the syntax of arguments.
+func Parse(s string) (*Line, error) {
+	l := &Line{}
+	if m := assignRegex.FindStringSubmatch(s); m != nil {
+		l.Var = m[1]
+		s = s[len(m[0]):]
+	}
+
+	words, err := Split(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(words) == 0 {
+		if l.Var != "" {
+			return nil, fmt.Errorf("missing command after %s =", l.Var)
+		}
+		return l, nil
+	}
+
+	l.Name, l.Args = words[0], words[1:]
+	return l, nil
+}
+
+// Split splits s into words separated by spaces. JSON objects and arrays are
+// single words, spaces included, and so are quoted strings: "..." with Go
+// escapes, or '...' taken literally.
+func Split(s string) ([]string, error) {
+	var words []string
+	for i := 0; ; {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return words, nil
+		}
+
+		switch s[i] {
+		case '{', '[':
+			end, err := matchBracket(s, i)
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, s[i:end])
+			i = end
+		case '"':
+			end, err := stringEnd(s, i)
+			if err != nil {
+				return nil, err
+			}
+			w, err := strconv.Unquote(s[i:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s: %w", s[i:end], err)
+			}
+			words = append(words, w)
+			i = end
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("unterminated string: missing '")
+			}
+			words = append(words, s[i+1:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && !isSpace(s[i]) {
+				i++
+			}
+			words = append(words, s[start:i])
+		}
+	}
+}
+
+// matchBracket returns the index after the bracket closing the one at start,
+// skipping the JSON strings in between.
+func matchBracket(s string, start int) (int, error) {
+	var stack []byte
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != s[i] {
+				return 0, fmt.Errorf("unexpected %c", s[i])
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i + 1, nil
+			}
+		case '"':
+			end, err := stringEnd(s, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end - 1
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated %c: missing %c", s[start], closing(s[start]))
+}
+
+// stringEnd returns the index after the quote closing the string at start.
+func stringEnd(s string, start int) (int, error) {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+
+	return 0, errors.New(`unterminated string: missing "`)
+}
+
+func closing(open byte) byte {
+	if open == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,162 @@
+package shell_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/shell"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want *shell.Line
+		err  string
+	}{
+		{line: "", want: &shell.Line{}},
+		{line: "  describe ", want: &shell.Line{Name: "describe", Args: []string{}}},
+		{
+			line: `db = create database {"name": "a b", "tags": ["x]", "}"]} extra`,
+			want: &shell.Line{Var: "db", Name: "create", Args: []string{"database", `{"name": "a b", "tags": ["x]", "}"]}`, "extra"}},
+		},
+		{
+			line: `x=read item "an \"id\"" 'it''s'`,
+			want: &shell.Line{Var: "x", Name: "read", Args: []string{"item", `an "id"`, "it", "s"}},
+		},
+		{line: "db =", err: "missing command after db ="},
+		{line: `create item {"a": [1}`, err: "unexpected }"},
+		{line: `create item {"a": 1`, err: "unterminated {: missing }"},
+		{line: `read item "id`, err: `unterminated string: missing "`},
+		{line: `read item 'id`, err: "unterminated string: missing '"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := shell.Parse(tt.line)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.err, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := shell.Vars{
+		"db": map[string]any{
+			"external_id": "db-1",
+			"properties":  map[string]any{"tags": []any{"a", "b"}, "size": float64(3)},
+		},
+		shell.LastVar: "last",
+	}
+
+	got, err := vars.Expand(`{"id": "${db.external_id}", "tag": "${db.properties.tags[-1]}", "size": ${db.properties.size}, "last": "${_}"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id": "db-1", "tag": "b", "size": 3, "last": "last"}`, got)
+
+	got, err = vars.Expand("${db.properties.tags}")
+	require.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, got)
+
+	for ref, msg := range map[string]string{
+		"${missing}":               "undefined variable missing",
+		"${db.name}":               "db.name: no value",
+		"${db.external_id.x}":      "db.external_id.x: not an object",
+		"${db.properties[0]}":      "db.properties[0]: not an array",
+		"${db.properties.tags[2]}": "db.properties.tags[2]: no value",
+	} {
+		_, err := vars.Expand(ref)
+		require.Error(t, err, ref)
+		assert.Equal(t, msg, err.Error(), ref)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c := &shell.Completer{
+		Commands: []string{"create", "reload", "read", "list"},
+		Args: func(command string, args []string) []string {
+			switch {
+			case len(args) == 0:
+				return []string{"database", "bucket"}
+			case command == "action" && len(args) == 2:
+				return []string{"restart", "resize"}
+			}
+			return nil
+		},
+		Vars: func() []string { return []string{"db", "_"} },
+	}
+
+	tests := []struct {
+		line    string
+		want    string
+		matches []string
+	}{
+		{line: "cr", want: "create "},
+		{line: "re", want: "re", matches: []string{"read", "reload"}},
+		{line: "rel", want: "reload "},
+		{line: "create d", want: "create database "},
+		{line: "x = create b", want: "x = create bucket "},
+		{line: "x=list ", want: "x=list ", matches: []string{"bucket", "database"}},
+		{line: "action database 1 res", want: "action database 1 res", matches: []string{"resize", "restart"}},
+		{line: "action database 1 rest", want: "action database 1 restart "},
+		{line: "read database ${d", want: "read database ${db}"},
+		{line: "zzz", want: "zzz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			line, pos, matches := c.Complete(tt.line, len(tt.line))
+			assert.Equal(t, tt.want, line)
+			assert.Equal(t, len(tt.want), pos)
+			assert.Equal(t, tt.matches, matches)
+		})
+	}
+
+	// The text after the cursor is kept.
+	line, pos, _ := c.Complete("cr item", 2)
+	assert.Equal(t, "create  item", line)
+	assert.Equal(t, 7, pos)
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tempest", "shell_history")
+
+	h, err := shell.LoadHistory(path)
+	require.NoError(t, err)
+	assert.Zero(t, h.Len())
+
+	h.Add("describe")
+	h.Add("describe")
+	h.Add("  ")
+	h.Add("list item")
+	require.Equal(t, 2, h.Len())
+	assert.Equal(t, "list item", h.At(0))
+	assert.Equal(t, "describe", h.At(1))
+
+	h, err = shell.LoadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, h.Len())
+	assert.Equal(t, "list item", h.At(0))
+
+	// The file is trimmed to the size of the history.
+	lines := make([]string, shell.HistorySize+10)
+	for i := range lines {
+		lines[i] = "read item " + strings.Repeat("x", i%5+1)
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+	h, err = shell.LoadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, shell.HistorySize, h.Len())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, shell.HistorySize, strings.Count(string(b), "\n"))
+}
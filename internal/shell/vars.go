@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LastVar is the variable holding the result of the last command.
+const LastVar = "_"
+
+// refRegex matches variable references, such as ${db} or
+// ${db.properties.tags[0]}.
+var refRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)((?:\.[A-Za-z0-9_-]+|\[-?[0-9]+\])*)\}`)
+
+// Vars holds the results of commands, as JSON documents.
+type Vars map[string]any
+
+// Expand replaces the variable references of s with their value: strings as
+// is, and other values as JSON.
+func (v Vars) Expand(s string) (string, error) {
+	var err error
+	out := refRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		m := refRegex.FindStringSubmatch(ref)
+		var value any
+		value, err = v.lookup(m[1], m[2])
+		if err != nil {
+			return ref
+		}
+
+		if s, ok := value.(string); ok {
+			return s
+		}
+		b, jsonErr := json.Marshal(value)
+		if jsonErr != nil {
+			err = jsonErr
+			return ref
+		}
+		return string(b)
+	})
+
+	return out, err
+}
+
+// lookup returns the value at path, such as .a[0], in the variable name.
+func (v Vars) lookup(name, path string) (any, error) {
+	value, ok := v[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined variable %s", name)
+	}
+
+	ref := name
+	for path != "" {
+		if path[0] == '.' {
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			key := path[:end]
+			path = path[end:]
+			ref += "." + key
+
+			m, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an object", ref)
+			}
+			if value, ok = m[key]; !ok {
+				return nil, fmt.Errorf("%s: no value", ref)
+			}
+			continue
+		}
+
+		end := strings.IndexByte(path, ']')
+		i, _ := strconv.Atoi(path[1:end])
+		ref += path[:end+1]
+		path = path[end+1:]
+
+		a, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an array", ref)
+		}
+		if i < 0 {
+			i += len(a)
+		}
+		if i < 0 || i >= len(a) {
+			return nil, fmt.Errorf("%s: no value", ref)
+		}
+		value = a[i]
+	}
+
+	return value, nil
+}
@@ -0,0 +1,251 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces the ignored values of snapshots.
+const Placeholder = "<ignored>"
+
+// Statuses of a checked snapshot.
+const (
+	// StatusCreated is the status of a snapshot stored for the first time.
+	StatusCreated = "created"
+	// StatusMatched is the status of a value matching its stored snapshot.
+	StatusMatched = "matched"
+	// StatusUpdated is the status of a stored snapshot replaced by the new
+	// value, with Store.Update.
+	StatusUpdated = "updated"
+	// StatusMismatch is the status of a value which does not match its stored
+	// snapshot.
+	StatusMismatch = "mismatch"
+)
+
+// Store stores snapshots as JSON files in a directory.
+type Store struct {
+	Dir string
+	// Ignore lists the paths of volatile values, such as generated IDs and
+	// timestamps, which are replaced by Placeholder before comparing.
+	Ignore []string
+	// Update replaces the stored snapshots which do not match, instead of
+	// reporting a mismatch.
+	Update bool
+}
+
+// Result of checking a value against its snapshot.
+type Result struct {
+	Name   string `json:"name" yaml:"name"`
+	Path   string `json:"path" yaml:"path"`
+	Status string `json:"status" yaml:"status"`
+	// Diff is the line diff from the stored snapshot to the value, when they
+	// differ.
+	Diff string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+// GetStatus returns the status of r, or an empty string when r is nil.
+func (r *Result) GetStatus() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Status
+}
+
+// Mismatch reports whether the value does not match its stored snapshot.
+func (r *Result) Mismatch() bool {
+	return r != nil && r.Status == StatusMismatch
+}
+
+// Check compares v, once normalized, with the snapshot stored under name. The
+// snapshot is stored when it does not exist yet, or when it differs and
+// s.Update is set. Names may contain slashes to group snapshots in
+// directories.
+func (s *Store) Check(name string, v any) (*Result, error) {
+	got, err := s.encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+
+	r := &Result{
+		Name: name,
+		Path: filepath.Join(s.Dir, filepath.FromSlash(name)+".json"),
+	}
+
+	want, err := os.ReadFile(r.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		r.Status = StatusCreated
+	case err != nil:
+		return nil, fmt.Errorf("read snapshot %s: %w", name, err)
+	case bytes.Equal(want, got):
+		r.Status = StatusMatched
+		return r, nil
+	default:
+		r.Diff = Diff(string(want), string(got))
+		if !s.Update {
+			r.Status = StatusMismatch
+			return r, nil
+		}
+		r.Status = StatusUpdated
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("write snapshot %s: %w", name, err)
+	}
+	if err := os.WriteFile(r.Path, got, 0o644); err != nil {
+		return nil, fmt.Errorf("write snapshot %s: %w", name, err)
+	}
+
+	return r, nil
+}
+
+// encode normalizes v and encodes it as indented JSON, with sorted keys.
+func (s *Store) encode(v any) ([]byte, error) {
+	n, err := Normalize(v, s.Ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Normalize converts v to its JSON representation, and replaces the values at
+// the ignore paths with Placeholder.
+//
+// Paths start with $, and are made of keys ($.resource.external_id),
+// wildcards matching any key or array item ($.resources[*].external_id or
+// $.properties.*) and recursive descents matching a key at any depth
+// ($..external_id).
+func Normalize(v any, ignore []string) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	for _, p := range ignore {
+		segments, err := parsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		doc = replace(doc, segments)
+	}
+
+	return doc, nil
+}
+
+// ValidatePath checks the syntax of an ignore path.
+func ValidatePath(path string) error {
+	_, err := parsePath(path)
+	return err
+}
+
+// segment is a single step of an ignore path. An empty key matches any key or
+// array item.
+type segment struct {
+	key string
+	// recursive segments match the key at any depth.
+	recursive bool
+}
+
+var keyRegex = regexp.MustCompile(`^[^.\[\]]+`)
+
+func parsePath(path string) ([]segment, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid ignore path %q: must start with $", path)
+	}
+
+	var segments []segment
+	for rest != "" {
+		var s segment
+		switch {
+		case strings.HasPrefix(rest, "[*]"):
+			rest = rest[3:]
+			segments = append(segments, s)
+			continue
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid ignore path %q: missing ']", path)
+			}
+			s.key = rest[2:end]
+			rest = rest[end+2:]
+			segments = append(segments, s)
+			continue
+		case strings.HasPrefix(rest, ".."):
+			s.recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		default:
+			return nil, fmt.Errorf("invalid ignore path %q: unexpected %q", path, rest[0])
+		}
+
+		key := keyRegex.FindString(rest)
+		if key == "" {
+			return nil, fmt.Errorf("invalid ignore path %q: empty key", path)
+		}
+		rest = rest[len(key):]
+		if key != "*" {
+			s.key = key
+		}
+		segments = append(segments, s)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid ignore path %q: the whole document cannot be ignored", path)
+	}
+
+	return segments, nil
+}
+
+// replace replaces the values of doc at the path made of segments with
+// Placeholder.
+func replace(doc any, segments []segment) any {
+	if len(segments) == 0 {
+		return Placeholder
+	}
+
+	s, rest := segments[0], segments[1:]
+
+	switch v := doc.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if s.key == "" || s.key == k {
+				v[k] = replace(e, rest)
+			} else if s.recursive {
+				v[k] = replace(e, segments)
+			}
+		}
+	case []any:
+		for i, e := range v {
+			if s.key == "" && !s.recursive {
+				v[i] = replace(e, rest)
+			} else if s.recursive {
+				v[i] = replace(e, segments)
+			}
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,88 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/snapshot"
+)
+
+func TestNormalize(t *testing.T) {
+	doc := map[string]any{
+		"resource": map[string]any{
+			"external_id": "1712345678",
+			"properties":  map[string]any{"name": "a", "created_at": "2024-01-01"},
+		},
+		"resources": []any{
+			map[string]any{"external_id": "1", "links": []any{map[string]any{"url": "u"}}},
+			map[string]any{"external_id": "2"},
+		},
+	}
+
+	n, err := snapshot.Normalize(doc, []string{"$..external_id", "$.resources[*].links", "$.resource.properties['created_at']"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"resource": map[string]any{
+			"external_id": snapshot.Placeholder,
+			"properties":  map[string]any{"name": "a", "created_at": snapshot.Placeholder},
+		},
+		"resources": []any{
+			map[string]any{"external_id": snapshot.Placeholder, "links": snapshot.Placeholder},
+			map[string]any{"external_id": snapshot.Placeholder},
+		},
+	}, n)
+
+	_, err = snapshot.Normalize(doc, []string{"resource.external_id"})
+	assert.EqualError(t, err, `invalid ignore path "resource.external_id": must start with $`)
+
+	_, err = snapshot.Normalize(doc, []string{"$"})
+	assert.EqualError(t, err, `invalid ignore path "$": the whole document cannot be ignored`)
+}
+
+func TestStoreCheck(t *testing.T) {
+	s := &snapshot.Store{
+		Dir:    t.TempDir(),
+		Ignore: []string{"$.id"},
+	}
+
+	r, err := s.Check("item/create", map[string]any{"id": "1", "name": "a", "size": 1})
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.StatusCreated, r.Status)
+	assert.Equal(t, filepath.Join(s.Dir, "item", "create.json"), r.Path)
+
+	b, err := os.ReadFile(r.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"id\": \"<ignored>\",\n  \"name\": \"a\",\n  \"size\": 1\n}\n", string(b))
+
+	// Ignored values may change.
+	r, err = s.Check("item/create", map[string]any{"id": "2", "name": "a", "size": 1})
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.StatusMatched, r.Status)
+
+	r, err = s.Check("item/create", map[string]any{"id": "3", "name": "b", "size": 1})
+	require.NoError(t, err)
+	assert.True(t, r.Mismatch())
+	assert.Equal(t, "@@ -1,5 +1,5 @@\n {\n   \"id\": \"<ignored>\",\n-  \"name\": \"a\",\n+  \"name\": \"b\",\n   \"size\": 1\n }\n", r.Diff)
+
+	s.Update = true
+	r, err = s.Check("item/create", map[string]any{"id": "3", "name": "b", "size": 1})
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.StatusUpdated, r.Status)
+
+	s.Update = false
+	r, err = s.Check("item/create", map[string]any{"id": "4", "name": "b", "size": 1})
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.StatusMatched, r.Status)
+}
+
+func TestDiff(t *testing.T) {
+	assert.Empty(t, snapshot.Diff("a\nb\n", "a\nb\n"))
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n"
+	assert.Equal(t, "@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -14,3 +14,4 @@\n 14\n 15\n 16\n+17\n", snapshot.Diff(a, b))
+}
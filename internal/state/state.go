@@ -0,0 +1,151 @@
+// Package state keeps track of the resources created by app tests, so that
+// they can be referred to by alias and cleaned up.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Dir is the directory of the state files, relative to the directory of the
+// Tempest configuration. It ignores itself in git.
+const Dir = ".tempest/state"
+
+// Resource is a resource created by a test.
+type Resource struct {
+	Type       string    `json:"type"`
+	ExternalID string    `json:"external_id"`
+	Alias      string    `json:"alias,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// State is the list of resources created by the tests of an app version, in
+// creation order.
+type State struct {
+	Resources []*Resource `json:"resources"`
+
+	path string
+}
+
+// Path returns the path of the state file of an app version.
+func Path(cfgDir, appID, version string) string {
+	return filepath.Join(cfgDir, Dir, appID+"-"+version+".json")
+}
+
+// Load reads the state file at path. A missing file is an empty state.
+func Load(path string) (*State, error) {
+	s := &State{path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parse state %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Path of the state file.
+func (s *State) Path() string {
+	return s.path
+}
+
+// Save writes the state file, creating its directory when needed.
+func (s *State) Save() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// The state is specific to the machine running the tests.
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(ignore, []byte("*\n"), 0o644); err != nil {
+			return err
+		}
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, append(b, '\n'), 0o644)
+}
+
+// Lookup returns the resource with the alias, or nil.
+func (s *State) Lookup(alias string) *Resource {
+	if alias == "" {
+		return nil
+	}
+
+	i := slices.IndexFunc(s.Resources, func(r *Resource) bool { return r.Alias == alias })
+	if i == -1 {
+		return nil
+	}
+
+	return s.Resources[i]
+}
+
+// CheckAlias returns an error when alias is used by another resource than the
+// one of the type and external ID.
+func (s *State) CheckAlias(alias, resourceType, externalID string) error {
+	r := s.Lookup(alias)
+	if r == nil || (r.Type == resourceType && r.ExternalID == externalID) {
+		return nil
+	}
+
+	return fmt.Errorf("alias %s is already used by %s resource %s", alias, r.Type, r.ExternalID)
+}
+
+// Add records a created resource, or updates its alias when it is already
+// recorded.
+func (s *State) Add(resourceType, externalID, alias string) error {
+	if err := s.CheckAlias(alias, resourceType, externalID); err != nil {
+		return err
+	}
+
+	if i := s.index(resourceType, externalID); i != -1 {
+		if alias != "" {
+			s.Resources[i].Alias = alias
+		}
+		return nil
+	}
+
+	s.Resources = append(s.Resources, &Resource{
+		Type:       resourceType,
+		ExternalID: externalID,
+		Alias:      alias,
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	})
+
+	return nil
+}
+
+// Remove forgets a resource, and reports whether it was recorded.
+func (s *State) Remove(resourceType, externalID string) bool {
+	i := s.index(resourceType, externalID)
+	if i == -1 {
+		return false
+	}
+
+	s.Resources = slices.Delete(s.Resources, i, i+1)
+	return true
+}
+
+func (s *State) index(resourceType, externalID string) int {
+	return slices.IndexFunc(s.Resources, func(r *Resource) bool {
+		return r.Type == resourceType && r.ExternalID == externalID
+	})
+}
@@ -0,0 +1,64 @@
+package state_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/state"
+)
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	path := state.Path(dir, "hello", "v1")
+	assert.Equal(t, filepath.Join(dir, ".tempest", "state", "hello-v1.json"), path)
+
+	s, err := state.Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, s.Resources)
+
+	require.NoError(t, s.Add("db", "1", "mydb"))
+	require.NoError(t, s.Add("db", "2", ""))
+	require.NoError(t, s.Add("bucket", "1", ""))
+
+	err = s.Add("db", "3", "mydb")
+	require.Error(t, err)
+	assert.Equal(t, "alias mydb is already used by db resource 1", err.Error())
+
+	// Recording a resource again only updates its alias.
+	require.NoError(t, s.Add("db", "2", "other"))
+	require.NoError(t, s.Save())
+
+	s, err = state.Load(path)
+	require.NoError(t, err)
+	require.Len(t, s.Resources, 3)
+	assert.Equal(t, "other", s.Resources[1].Alias)
+	assert.False(t, s.Resources[0].CreatedAt.IsZero())
+
+	r := s.Lookup("mydb")
+	require.NotNil(t, r)
+	assert.Equal(t, "db", r.Type)
+	assert.Equal(t, "1", r.ExternalID)
+	assert.Nil(t, s.Lookup("missing"))
+	assert.Nil(t, s.Lookup(""))
+
+	assert.True(t, s.Remove("db", "1"))
+	assert.False(t, s.Remove("db", "1"))
+	assert.Nil(t, s.Lookup("mydb"))
+	assert.Len(t, s.Resources, 2)
+
+	ignore, err := os.ReadFile(filepath.Join(dir, state.Dir, ".gitignore"))
+	require.NoError(t, err)
+	assert.Equal(t, "*\n", string(ignore))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+
+	_, err := state.Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parse state")
+}
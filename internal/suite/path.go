@@ -0,0 +1,103 @@
+package suite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment is a single step of a JSONPath: either an object key, or an array
+// index when key is empty.
+type segment struct {
+	key   string
+	index int
+}
+
+// parsePath parses the subset of JSONPath supported by suites: the root "$",
+// dot notation ($.a.b), bracket notation ($['a']) and array indexes ($.a[0]).
+// Negative indexes count from the end of the array.
+func parsePath(path string) ([]segment, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid path %q: must start with $", path)
+	}
+
+	var segments []segment
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty key", path)
+			}
+			segments = append(segments, segment{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, segment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: invalid index %q", path, inner)
+			}
+			segments = append(segments, segment{index: i})
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", path, rest[0])
+		}
+	}
+
+	return segments, nil
+}
+
+var errNoValue = errors.New("no value")
+
+// lookup returns the value at path in doc.
+func lookup(doc any, path string) (any, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := doc
+	for _, s := range segments {
+		if s.key != "" {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, errNoValue
+			}
+			if v, ok = m[s.key]; !ok {
+				return nil, errNoValue
+			}
+			continue
+		}
+
+		a, ok := v.([]any)
+		if !ok {
+			return nil, errNoValue
+		}
+		i := s.index
+		if i < 0 {
+			i += len(a)
+		}
+		if i < 0 || i >= len(a) {
+			return nil, errNoValue
+		}
+		v = a[i]
+	}
+
+	return v, nil
+}
@@ -0,0 +1,221 @@
+package suite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Step statuses reported in summaries.
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Summary is the machine readable report of a suite run.
+type Summary struct {
+	Passed     int               `json:"passed" yaml:"passed"`
+	Failed     int               `json:"failed" yaml:"failed"`
+	Skipped    int               `json:"skipped" yaml:"skipped"`
+	DurationMS float64           `json:"duration_ms" yaml:"duration_ms"`
+	Scenarios  []ScenarioSummary `json:"scenarios" yaml:"scenarios"`
+}
+
+// ScenarioSummary is the report of a scenario.
+type ScenarioSummary struct {
+	Name       string        `json:"name" yaml:"name"`
+	Status     string        `json:"status" yaml:"status"`
+	DurationMS float64       `json:"duration_ms" yaml:"duration_ms"`
+	Steps      []StepSummary `json:"steps" yaml:"steps"`
+}
+
+// StepSummary is the report of a step.
+type StepSummary struct {
+	Name       string   `json:"name" yaml:"name"`
+	Status     string   `json:"status" yaml:"status"`
+	DurationMS float64  `json:"duration_ms" yaml:"duration_ms"`
+	Failures   []string `json:"failures,omitempty" yaml:"failures,omitempty"`
+	Error      string   `json:"error,omitempty" yaml:"error,omitempty"`
+	// The status of the snapshot of the response, when snapshots are enabled.
+	Snapshot string `json:"snapshot,omitempty" yaml:"snapshot,omitempty"`
+}
+
+func (r *StepResult) status() string {
+	switch {
+	case r.Skipped:
+		return StatusSkipped
+	case r.Failed():
+		return StatusFailed
+	default:
+		return StatusPassed
+	}
+}
+
+// Duration returns the time spent running the steps of the scenario.
+func (r *ScenarioResult) Duration() time.Duration {
+	var d time.Duration
+	for _, st := range r.Steps {
+		d += st.Duration
+	}
+
+	return d
+}
+
+// Duration returns the time spent running the steps of the suite.
+func (r *Result) Duration() time.Duration {
+	var d time.Duration
+	for _, sc := range r.Scenarios {
+		d += sc.Duration()
+	}
+
+	return d
+}
+
+// Summary returns the machine readable report of the run.
+func (r *Result) Summary() *Summary {
+	s := &Summary{
+		DurationMS: milliseconds(r.Duration()),
+		Scenarios:  make([]ScenarioSummary, 0, len(r.Scenarios)),
+	}
+	s.Passed, s.Failed, s.Skipped = r.Counts()
+
+	for _, sc := range r.Scenarios {
+		scs := ScenarioSummary{
+			Name:       sc.Name,
+			Status:     StatusPassed,
+			DurationMS: milliseconds(sc.Duration()),
+			Steps:      make([]StepSummary, 0, len(sc.Steps)),
+		}
+		if sc.Failed() {
+			scs.Status = StatusFailed
+		}
+
+		for _, st := range sc.Steps {
+			sts := StepSummary{
+				Name:       st.Name,
+				Status:     st.status(),
+				DurationMS: milliseconds(st.Duration),
+				Failures:   st.Failures,
+			}
+			if st.Err != nil {
+				sts.Error = st.Err.Error()
+			}
+			if st.Snapshot != nil {
+				sts.Snapshot = st.Snapshot.Status
+			}
+			scs.Steps = append(scs.Steps, sts)
+		}
+
+		s.Scenarios = append(s.Scenarios, scs)
+	}
+
+	return s
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the result as a JUnit XML report. Scenarios are reported
+// as test suites, and steps as test cases. Failed assertions are reported as
+// failures, and steps that could not run or failed unexpectedly as errors.
+func (r *Result) WriteJUnit(w io.Writer, name string) error {
+	report := junitTestSuites{
+		Name: name,
+		Time: seconds(r.Duration()),
+	}
+
+	for _, sc := range r.Scenarios {
+		suite := junitTestSuite{
+			Name: sc.Name,
+			Time: seconds(sc.Duration()),
+		}
+
+		for _, st := range sc.Steps {
+			tc := junitTestCase{
+				Name:      st.Name,
+				ClassName: sc.Name,
+				Time:      seconds(st.Duration),
+			}
+
+			switch {
+			case st.Skipped:
+				tc.Skipped = &junitMessage{Message: "a previous step failed"}
+				suite.Skipped++
+			case st.Err != nil:
+				tc.Error = &junitMessage{Message: st.Err.Error()}
+				suite.Errors++
+			case len(st.Failures) > 0:
+				tc.Failure = &junitMessage{
+					Message: st.Failures[0],
+					Body:    strings.Join(st.Failures, "\n"),
+				}
+				suite.Failures++
+			}
+
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
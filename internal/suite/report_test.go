@@ -0,0 +1,73 @@
+package suite_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/suite"
+)
+
+var reportResult = &suite.Result{
+	Scenarios: []*suite.ScenarioResult{
+		{
+			Name: "lifecycle",
+			Steps: []*suite.StepResult{
+				{Name: "create item", Duration: 1500 * time.Microsecond},
+				{Name: "read item", Duration: 2 * time.Millisecond, Failures: []string{"$.name: expected a value", `$.size: expected 3, got 4`}},
+				{Name: "delete item", Skipped: true},
+			},
+		},
+		{
+			Name: "errors",
+			Steps: []*suite.StepResult{
+				{Name: "read item", Err: errors.New("external_id: undefined variable id")},
+			},
+		},
+	},
+}
+
+func TestSummary(t *testing.T) {
+	s := reportResult.Summary()
+
+	assert.Equal(t, 1, s.Passed)
+	assert.Equal(t, 2, s.Failed)
+	assert.Equal(t, 1, s.Skipped)
+	assert.Equal(t, 3.5, s.DurationMS)
+
+	require.Len(t, s.Scenarios, 2)
+	assert.Equal(t, suite.StatusFailed, s.Scenarios[0].Status)
+	assert.Equal(t, []suite.StepSummary{
+		{Name: "create item", Status: suite.StatusPassed, DurationMS: 1.5},
+		{Name: "read item", Status: suite.StatusFailed, DurationMS: 2, Failures: []string{"$.name: expected a value", `$.size: expected 3, got 4`}},
+		{Name: "delete item", Status: suite.StatusSkipped},
+	}, s.Scenarios[0].Steps)
+	assert.Equal(t, "external_id: undefined variable id", s.Scenarios[1].Steps[0].Error)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b strings.Builder
+	require.NoError(t, reportResult.WriteJUnit(&b, "hello:v1"))
+
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="hello:v1" tests="4" failures="1" errors="1" skipped="1" time="0.004">
+  <testsuite name="lifecycle" tests="3" failures="1" errors="0" skipped="1" time="0.004">
+    <testcase name="create item" classname="lifecycle" time="0.002"></testcase>
+    <testcase name="read item" classname="lifecycle" time="0.002">
+      <failure message="$.name: expected a value">$.name: expected a value&#xA;$.size: expected 3, got 4</failure>
+    </testcase>
+    <testcase name="delete item" classname="lifecycle" time="0.000">
+      <skipped message="a previous step failed"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="errors" tests="1" failures="0" errors="1" skipped="0" time="0.000">
+    <testcase name="read item" classname="errors" time="0.000">
+      <error message="external_id: undefined variable id"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
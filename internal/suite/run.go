@@ -0,0 +1,547 @@
+package suite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/tempestdx/cli/internal/schema"
+	"github.com/tempestdx/cli/internal/snapshot"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var varRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// maxListPages is the number of pages a list step fetches at most.
+const maxListPages = 100
+
+type Options struct {
+	// Metadata sent with every operation.
+	Metadata *appv1.Metadata
+	// Environment variables sent with every operation, in addition to the
+	// suite environment. They take precedence over the suite environment.
+	Env []*appv1.EnvironmentVariable
+	// Properties schemas keyed by resource type. When set, the properties
+	// returned by create, read, update and list operations are validated.
+	PropertiesSchemas map[string]*schema.Schema
+	// When set, the response of each successful step is compared with its
+	// snapshot, stored under <scenario>/<step>. The ignore paths of the suite
+	// are added to the ignore paths of the store.
+	Snapshots *snapshot.Store
+	// When set, Track is called after each successful create or delete
+	// operation, with the type and external ID of the resource.
+	Track func(operation, resourceType, externalID string)
+}
+
+type Result struct {
+	Scenarios []*ScenarioResult
+}
+
+type ScenarioResult struct {
+	Name  string
+	Steps []*StepResult
+}
+
+type StepResult struct {
+	Name     string
+	Duration time.Duration
+	// Set when the step was not run because a previous step failed.
+	Skipped bool
+	// Failed assertions.
+	Failures []string
+	// Set when the step could not be run, or the operation failed unexpectedly.
+	Err error
+	// The comparison of the response with its snapshot, when snapshots are
+	// enabled.
+	Snapshot *snapshot.Result
+}
+
+func (r *StepResult) Failed() bool {
+	return r.Err != nil || len(r.Failures) > 0
+}
+
+func (r *ScenarioResult) Failed() bool {
+	return slices.ContainsFunc(r.Steps, (*StepResult).Failed)
+}
+
+func (r *Result) Failed() bool {
+	return slices.ContainsFunc(r.Scenarios, (*ScenarioResult).Failed)
+}
+
+// Counts returns the number of passed, failed and skipped steps.
+func (r *Result) Counts() (passed, failed, skipped int) {
+	for _, sc := range r.Scenarios {
+		for _, st := range sc.Steps {
+			switch {
+			case st.Skipped:
+				skipped++
+			case st.Failed():
+				failed++
+			default:
+				passed++
+			}
+		}
+	}
+
+	return passed, failed, skipped
+}
+
+// Run runs the scenarios of the suite in order against client.
+func Run(ctx context.Context, client appv1connect.AppServiceClient, s *Suite, opts Options) *Result {
+	if opts.Snapshots != nil {
+		store := *opts.Snapshots
+		store.Ignore = append(slices.Clone(store.Ignore), s.SnapshotIgnore...)
+		opts.Snapshots = &store
+	}
+
+	r := &runner{
+		client: client,
+		suite:  s,
+		opts:   opts,
+	}
+
+	result := &Result{}
+	scenarios := make(map[string]int)
+	for _, sc := range s.Scenarios {
+		result.Scenarios = append(result.Scenarios, r.runScenario(ctx, sc, snapshotName(sc.Name, scenarios)))
+	}
+
+	return result
+}
+
+type runner struct {
+	client appv1connect.AppServiceClient
+	suite  *Suite
+	opts   Options
+}
+
+var unsafeRegex = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// snapshotName returns a file name for the snapshot of name, made unique by a
+// counter suffix among the names already in use.
+func snapshotName(name string, used map[string]int) string {
+	base := strings.Trim(unsafeRegex.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if base == "" {
+		base = "unnamed"
+	}
+
+	used[base]++
+	if n := used[base]; n > 1 {
+		return fmt.Sprintf("%s-%d", base, n)
+	}
+
+	return base
+}
+
+func (r *runner) runScenario(ctx context.Context, sc *Scenario, dir string) *ScenarioResult {
+	vars := make(map[string]any, len(r.suite.Vars)+len(sc.Vars))
+	maps.Copy(vars, r.suite.Vars)
+	maps.Copy(vars, sc.Vars)
+
+	result := &ScenarioResult{Name: sc.Name}
+
+	var failed bool
+	steps := make(map[string]int)
+	for _, st := range sc.Steps {
+		key := dir + "/" + snapshotName(st.Name, steps)
+		if failed && !st.Always {
+			result.Steps = append(result.Steps, &StepResult{Name: st.Name, Skipped: true})
+			continue
+		}
+
+		sr := r.runStep(ctx, st, vars, key)
+		result.Steps = append(result.Steps, sr)
+		failed = failed || sr.Failed()
+	}
+
+	return result
+}
+
+func (r *runner) runStep(ctx context.Context, st *Step, vars map[string]any, key string) *StepResult {
+	result := &StepResult{Name: st.Name}
+
+	start := time.Now()
+	doc, err := r.execute(ctx, st, vars)
+	result.Duration = time.Since(start)
+
+	var ce *connect.Error
+	if err != nil && !errors.As(err, &ce) {
+		// The step could not be built, such as an undefined variable.
+		result.Err = err
+		return result
+	}
+
+	if st.ExpectError != nil {
+		if err == nil {
+			result.Failures = append(result.Failures, "expected the operation to fail, but it succeeded")
+			return result
+		}
+
+		if st.ExpectError.Code != "" && ce.Code().String() != st.ExpectError.Code {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected error code %s, got %s: %s", st.ExpectError.Code, ce.Code(), ce.Message()))
+		}
+		if st.ExpectError.Matches != "" && !regexp.MustCompile(st.ExpectError.Matches).MatchString(ce.Message()) {
+			result.Failures = append(result.Failures, fmt.Sprintf("expected error matching %q, got %q", st.ExpectError.Matches, ce.Message()))
+		}
+		return result
+	}
+
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if st.Operation != OperationDelete {
+		result.Failures = append(result.Failures, r.checkProperties(st, doc)...)
+	}
+
+	// Captured variables can be used by the assertions of the step.
+	for _, name := range slices.Sorted(maps.Keys(st.Capture)) {
+		v, err := lookup(doc, st.Capture[name])
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("capture %s: %s: %s", name, st.Capture[name], err))
+			continue
+		}
+		vars[name] = v
+	}
+
+	for _, a := range st.Expect {
+		if failure := r.check(a, doc, vars); failure != "" {
+			result.Failures = append(result.Failures, failure)
+		}
+	}
+
+	if r.opts.Snapshots != nil {
+		result.Snapshot, err = r.opts.Snapshots.Check(key, doc)
+		if err != nil {
+			result.Err = err
+		} else if result.Snapshot.Mismatch() {
+			result.Failures = append(result.Failures, fmt.Sprintf("the response does not match the snapshot %s:\n%s", result.Snapshot.Path, result.Snapshot.Diff))
+		}
+	}
+
+	return result
+}
+
+// execute runs the operation of the step, and returns its response as a JSON
+// document used by assertions and captures.
+func (r *runner) execute(ctx context.Context, st *Step, vars map[string]any) (any, error) {
+	externalID, err := interpolateString(st.ExternalID, vars)
+	if err != nil {
+		return nil, fmt.Errorf("external_id: %w", err)
+	}
+
+	env, err := r.environment(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &appv1.Resource{
+		Type:       st.Type,
+		ExternalId: externalID,
+	}
+
+	if st.Operation == OperationList {
+		var resources []any
+		var next string
+		for page := 0; ; page++ {
+			if page == maxListPages {
+				return nil, fmt.Errorf("the app returned more than %d pages", maxListPages)
+			}
+
+			res, err := r.client.ListResources(ctx, connect.NewRequest(&appv1.ListResourcesRequest{
+				Metadata: r.opts.Metadata,
+				Resource: resource,
+				Next:     next,
+			}))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, res := range res.Msg.GetResources() {
+				resources = append(resources, resourceDocument(res))
+			}
+
+			if res.Msg.Next != "" && res.Msg.Next == next {
+				return nil, fmt.Errorf("the app returned the next page token %q of the page it was given", next)
+			}
+			if res.Msg.Next == "" {
+				break
+			}
+			next = res.Msg.Next
+		}
+
+		return map[string]any{"resources": resources}, nil
+	}
+
+	req := &appv1.ExecuteResourceOperationRequest{
+		Metadata:             r.opts.Metadata,
+		Resource:             resource,
+		Operation:            operations[st.Operation],
+		EnvironmentVariables: env,
+	}
+
+	if st.Input != nil {
+		input, err := interpolate(st.Input, vars)
+		if err != nil {
+			return nil, fmt.Errorf("input: %w", err)
+		}
+
+		req.Input, err = structpb.NewStruct(normalize(input).(map[string]any))
+		if err != nil {
+			return nil, fmt.Errorf("input: %w", err)
+		}
+	}
+
+	res, err := r.client.ExecuteResourceOperation(ctx, connect.NewRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	if r.opts.Track != nil {
+		switch st.Operation {
+		case OperationCreate:
+			r.opts.Track(st.Operation, st.Type, res.Msg.GetResource().GetExternalId())
+		case OperationDelete:
+			r.opts.Track(st.Operation, st.Type, externalID)
+		}
+	}
+
+	return resourceDocument(res.Msg.GetResource()), nil
+}
+
+var operations = map[string]appv1.ResourceOperation{
+	OperationCreate: appv1.ResourceOperation_RESOURCE_OPERATION_CREATE,
+	OperationRead:   appv1.ResourceOperation_RESOURCE_OPERATION_READ,
+	OperationUpdate: appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE,
+	OperationDelete: appv1.ResourceOperation_RESOURCE_OPERATION_DELETE,
+}
+
+func (r *runner) environment(vars map[string]any) ([]*appv1.EnvironmentVariable, error) {
+	env := make([]*appv1.EnvironmentVariable, 0, len(r.suite.Env)+len(r.opts.Env))
+	for _, k := range slices.Sorted(maps.Keys(r.suite.Env)) {
+		if slices.ContainsFunc(r.opts.Env, func(e *appv1.EnvironmentVariable) bool { return e.Key == k }) {
+			continue
+		}
+
+		v, err := interpolateString(r.suite.Env[k], vars)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+
+		env = append(env, &appv1.EnvironmentVariable{
+			Key:   k,
+			Value: v,
+			Type:  appv1.EnvironmentVariableType_ENVIRONMENT_VARIABLE_TYPE_VAR,
+		})
+	}
+
+	return append(env, r.opts.Env...), nil
+}
+
+// checkProperties validates the properties of the resources in doc against
+// the properties schema of the step type.
+func (r *runner) checkProperties(st *Step, doc any) []string {
+	sch, ok := r.opts.PropertiesSchemas[st.Type]
+	if !ok {
+		return nil
+	}
+
+	paths := []string{"$.properties"}
+	if st.Operation == OperationList {
+		v, _ := lookup(doc, "$.resources")
+		resources, _ := v.([]any)
+		paths = paths[:0]
+		for i := range resources {
+			paths = append(paths, fmt.Sprintf("$.resources[%d].properties", i))
+		}
+	}
+
+	var failures []string
+	for _, path := range paths {
+		properties, err := lookup(doc, path)
+		if err != nil {
+			continue
+		}
+
+		var ve *schema.ValidationError
+		if err := sch.Validate(properties); errors.As(err, &ve) {
+			for _, v := range ve.Violations {
+				failures = append(failures, fmt.Sprintf("%s: does not match the properties schema: %s", path, v))
+			}
+		} else if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", path, err))
+		}
+	}
+
+	return failures
+}
+
+// check returns a description of the failure when the assertion does not hold
+// for doc, or an empty string.
+func (r *runner) check(a *Assertion, doc any, vars map[string]any) string {
+	v, err := lookup(doc, a.Path)
+	exists := err == nil
+
+	if a.Exists != nil && *a.Exists != exists {
+		if exists {
+			return fmt.Sprintf("%s: expected no value, got %s", a.Path, format(v))
+		}
+		return fmt.Sprintf("%s: expected a value", a.Path)
+	}
+
+	if a.Equals == nil && a.Matches == "" {
+		if a.Exists == nil && !exists {
+			return fmt.Sprintf("%s: expected a value", a.Path)
+		}
+		return ""
+	}
+
+	if !exists {
+		return fmt.Sprintf("%s: expected a value", a.Path)
+	}
+
+	if a.Equals != nil {
+		expected, err := interpolate(a.Equals, vars)
+		if err != nil {
+			return fmt.Sprintf("%s: %s", a.Path, err)
+		}
+
+		if !reflect.DeepEqual(normalize(expected), normalize(v)) {
+			return fmt.Sprintf("%s: expected %s, got %s", a.Path, format(expected), format(v))
+		}
+	}
+
+	if a.Matches != "" {
+		pattern, err := interpolateString(a.Matches, vars)
+		if err != nil {
+			return fmt.Sprintf("%s: %s", a.Path, err)
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Sprintf("%s: invalid regular expression: %s", a.Path, err)
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			s = format(v)
+		}
+		if !re.MatchString(s) {
+			return fmt.Sprintf("%s: expected a value matching %q, got %s", a.Path, pattern, format(v))
+		}
+	}
+
+	return ""
+}
+
+func resourceDocument(r *appv1.Resource) map[string]any {
+	links := make([]any, 0, len(r.GetLinks()))
+	for _, l := range r.GetLinks() {
+		links = append(links, map[string]any{
+			"title": l.GetTitle(),
+			"url":   l.GetUrl(),
+			"type":  strings.ToLower(strings.TrimPrefix(l.GetType().String(), "LINK_TYPE_")),
+		})
+	}
+
+	return map[string]any{
+		"external_id":  r.GetExternalId(),
+		"display_name": r.GetDisplayName(),
+		"type":         r.GetType(),
+		"properties":   r.GetProperties().AsMap(),
+		"links":        links,
+	}
+}
+
+// interpolate replaces the ${name} variable references in the strings of v.
+// A string made of a single reference is replaced by the raw variable value,
+// so that numbers, booleans and objects keep their type.
+func interpolate(v any, vars map[string]any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if m := varRegex.FindStringSubmatch(v); m != nil && m[0] == v {
+			value, ok := vars[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("undefined variable %s", m[1])
+			}
+			return value, nil
+		}
+		return interpolateString(v, vars)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			i, err := interpolate(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = i
+		}
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, e := range v {
+			i, err := interpolate(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, i)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func interpolateString(s string, vars map[string]any) (string, error) {
+	var err error
+	out := varRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := varRegex.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			err = fmt.Errorf("undefined variable %s", name)
+			return ref
+		}
+		if s, ok := value.(string); ok {
+			return s
+		}
+		return format(value)
+	})
+
+	return out, err
+}
+
+// normalize converts v to its JSON representation, so that values decoded
+// from YAML compare equal to the values returned by the app.
+func normalize(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return v
+	}
+
+	return out
+}
+
+func format(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
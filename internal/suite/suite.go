@@ -0,0 +1,204 @@
+package suite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/tempestdx/cli/internal/snapshot"
+	"gopkg.in/yaml.v3"
+)
+
+// Operations that can be used in a step.
+const (
+	OperationCreate = "create"
+	OperationRead   = "read"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+	OperationList   = "list"
+)
+
+// Suite is a declarative test suite, run against a single running app.
+type Suite struct {
+	// Variables available to every scenario.
+	Vars map[string]any `yaml:"vars,omitempty"`
+	// Environment variables passed to every operation.
+	Env map[string]string `yaml:"env,omitempty"`
+	// Paths of volatile values, such as generated IDs, ignored when comparing
+	// responses with their snapshots. See snapshot.Normalize for the syntax.
+	SnapshotIgnore []string `yaml:"snapshot_ignore,omitempty"`
+	// Scenarios are run in order. Each scenario starts from the suite variables.
+	Scenarios []*Scenario `yaml:"scenarios"`
+}
+
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Variables available to the steps of this scenario, overriding the suite variables.
+	Vars map[string]any `yaml:"vars,omitempty"`
+	// Steps are run in order. Once a step fails, the remaining steps are
+	// skipped, except the ones marked with always.
+	Steps []*Step `yaml:"steps"`
+}
+
+type Step struct {
+	Name      string `yaml:"name,omitempty"`
+	Operation string `yaml:"operation"`
+	Type      string `yaml:"type"`
+	// The external ID of the resource. Required by read, update and delete.
+	ExternalID string `yaml:"external_id,omitempty"`
+	// The input of create and update operations.
+	Input map[string]any `yaml:"input,omitempty"`
+	// Variables to capture from the response, keyed by variable name, with
+	// the JSONPath of the value as value.
+	Capture map[string]string `yaml:"capture,omitempty"`
+	// Assertions on the response.
+	Expect []*Assertion `yaml:"expect,omitempty"`
+	// When set, the operation is expected to fail.
+	ExpectError *ErrorAssertion `yaml:"expect_error,omitempty"`
+	// Run the step even when a previous step of the scenario failed, such as
+	// steps cleaning up created resources.
+	Always bool `yaml:"always,omitempty"`
+}
+
+// Assertion checks the value at a JSONPath of the response. The response of
+// create, read, update and delete operations has the following shape:
+//
+//	{"external_id": "", "display_name": "", "type": "", "properties": {}, "links": [{"title": "", "url": "", "type": ""}]}
+//
+// The response of list operations is {"resources": [...]}, each resource
+// having the shape above.
+type Assertion struct {
+	Path string `yaml:"path"`
+	// The expected value, compared as JSON.
+	Equals any `yaml:"equals,omitempty"`
+	// A regular expression matched against the value.
+	Matches string `yaml:"matches,omitempty"`
+	// Whether a value is expected to exist at the path.
+	Exists *bool `yaml:"exists,omitempty"`
+}
+
+// ErrorAssertion checks the error returned by an operation.
+type ErrorAssertion struct {
+	// The expected Connect error code, such as "invalid_argument" or "not_found".
+	Code string `yaml:"code,omitempty"`
+	// A regular expression matched against the error message.
+	Matches string `yaml:"matches,omitempty"`
+}
+
+// Load reads and parses the suite file at path.
+func Load(path string) (*Suite, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(b)
+}
+
+// Parse parses and validates a YAML suite.
+func Parse(b []byte) (*Suite, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+
+	var s Suite
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("parse suite: %w", err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *Suite) validate() error {
+	if len(s.Scenarios) == 0 {
+		return errors.New("suite has no scenarios")
+	}
+
+	for _, p := range s.SnapshotIgnore {
+		if err := snapshot.ValidatePath(p); err != nil {
+			return fmt.Errorf("snapshot_ignore: %w", err)
+		}
+	}
+
+	for i, sc := range s.Scenarios {
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("scenario %d", i+1)
+		}
+
+		if len(sc.Steps) == 0 {
+			return fmt.Errorf("%s: no steps", sc.Name)
+		}
+
+		for j, st := range sc.Steps {
+			if err := st.validate(); err != nil {
+				return fmt.Errorf("%s: step %d: %w", sc.Name, j+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (st *Step) validate() error {
+	switch st.Operation {
+	case OperationCreate, OperationUpdate:
+	case OperationRead, OperationDelete, OperationList:
+		if st.Input != nil {
+			return fmt.Errorf("input is not supported by the %s operation", st.Operation)
+		}
+	case "":
+		return errors.New("operation is required")
+	default:
+		return fmt.Errorf("invalid operation %q. Accepted values: '%s', '%s', '%s', '%s', '%s'", st.Operation, OperationCreate, OperationRead, OperationUpdate, OperationDelete, OperationList)
+	}
+
+	if st.Type == "" {
+		return errors.New("type is required")
+	}
+
+	switch st.Operation {
+	case OperationRead, OperationUpdate, OperationDelete:
+		if st.ExternalID == "" {
+			return fmt.Errorf("external_id is required by the %s operation", st.Operation)
+		}
+	}
+
+	if st.Name == "" {
+		st.Name = st.Operation + " " + st.Type
+	}
+
+	for name, path := range st.Capture {
+		if _, err := parsePath(path); err != nil {
+			return fmt.Errorf("capture %s: %w", name, err)
+		}
+	}
+
+	for _, a := range st.Expect {
+		if _, err := parsePath(a.Path); err != nil {
+			return fmt.Errorf("expect: %w", err)
+		}
+		if a.Matches != "" {
+			if _, err := regexp.Compile(a.Matches); err != nil {
+				return fmt.Errorf("expect %s: invalid regular expression: %w", a.Path, err)
+			}
+		}
+	}
+
+	if st.ExpectError != nil {
+		if st.ExpectError.Matches != "" {
+			if _, err := regexp.Compile(st.ExpectError.Matches); err != nil {
+				return fmt.Errorf("expect_error: invalid regular expression: %w", err)
+			}
+		}
+		if len(st.Capture) > 0 {
+			return errors.New("capture cannot be used with expect_error")
+		}
+	}
+
+	return nil
+}
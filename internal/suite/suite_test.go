@@ -0,0 +1,433 @@
+package suite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/schema"
+	"github.com/tempestdx/cli/internal/snapshot"
+	"github.com/tempestdx/cli/internal/suite"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"github.com/tempestdx/sdk-go/app"
+)
+
+var itemSchema = []byte(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"size": {"type": "integer"}
+	},
+	"required": ["name"]
+}`)
+
+// newTestClient starts an app managing items in memory, and returns a client
+// connected to it.
+func newTestClient(t *testing.T) appv1connect.AppServiceClient {
+	t.Helper()
+
+	items := make(map[string]map[string]any)
+	var nextID int
+
+	toResponse := func(id string) *app.OperationResponse {
+		return &app.OperationResponse{
+			Resource: &app.Resource{
+				ExternalID:  id,
+				DisplayName: items[id]["name"].(string),
+				Type:        "item",
+				Links: []*app.Link{
+					{Title: "Item", URL: "https://example.com/items/" + id, Type: app.LinkTypeExternal},
+				},
+				Properties: items[id],
+			},
+		}
+	}
+
+	rd := app.ResourceDefinition{
+		Type:             "item",
+		DisplayName:      "Item",
+		PropertiesSchema: app.MustParseJSONSchema(itemSchema),
+	}
+	rd.CreateFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		nextID++
+		id := fmt.Sprintf("item-%d", nextID)
+		items[id] = req.Input
+		return toResponse(id), nil
+	}, app.MustParseJSONSchema(itemSchema))
+	rd.ReadFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		if _, ok := items[req.Resource.ExternalID]; !ok {
+			return nil, errors.New("item not found")
+		}
+		return toResponse(req.Resource.ExternalID), nil
+	})
+	rd.DeleteFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		res := toResponse(req.Resource.ExternalID)
+		delete(items, req.Resource.ExternalID)
+		return res, nil
+	})
+	rd.ListFn(func(ctx context.Context, req *app.ListRequest) (*app.ListResponse, error) {
+		res := &app.ListResponse{}
+		for id := range items {
+			res.Resources = append(res.Resources, toResponse(id).Resource)
+		}
+		return res, nil
+	})
+
+	a := app.New(app.WithResourceDefinition(rd))
+
+	mux := http.NewServeMux()
+	mux.Handle(appv1connect.AppServiceExecuteResourceOperationProcedure, connect.NewUnaryHandler(appv1connect.AppServiceExecuteResourceOperationProcedure, a.ExecuteResourceOperation))
+	mux.Handle(appv1connect.AppServiceListResourcesProcedure, connect.NewUnaryHandler(appv1connect.AppServiceListResourcesProcedure, a.ListResources))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return appv1connect.NewAppServiceClient(srv.Client(), srv.URL)
+}
+
+func TestRun(t *testing.T) {
+	s, err := suite.Parse([]byte(`
+vars:
+  name: widget
+scenarios:
+  - name: lifecycle
+    steps:
+      - operation: create
+        type: item
+        input:
+          name: ${name}
+          size: 3
+        capture:
+          id: $.external_id
+        expect:
+          - path: $.properties.size
+            equals: 3
+          - path: $.display_name
+            equals: ${name}
+          - path: $.links[0].url
+            matches: ^https://example.com/items/${id}$
+      - operation: read
+        type: item
+        external_id: ${id}
+        expect:
+          - path: $['properties']['name']
+            equals: widget
+          - path: $.properties.color
+            exists: false
+      - operation: list
+        type: item
+        expect:
+          - path: $.resources[-1].external_id
+            equals: ${id}
+      - operation: delete
+        type: item
+        external_id: ${id}
+      - operation: read
+        type: item
+        external_id: ${id}
+        expect_error:
+          code: internal
+          matches: not found
+`))
+	require.NoError(t, err)
+
+	var tracked []string
+	result := suite.Run(context.Background(), newTestClient(t), s, suite.Options{
+		Track: func(operation, resourceType, externalID string) {
+			tracked = append(tracked, operation+" "+resourceType+" "+externalID)
+		},
+	})
+
+	for _, st := range result.Scenarios[0].Steps {
+		assert.False(t, st.Failed(), "%s: %v %v", st.Name, st.Err, st.Failures)
+	}
+	assert.False(t, result.Failed())
+	assert.Equal(t, []string{"create item item-1", "delete item item-1"}, tracked)
+
+	passed, failed, skipped := result.Counts()
+	assert.Equal(t, 5, passed)
+	assert.Zero(t, failed)
+	assert.Zero(t, skipped)
+}
+
+func TestRunFailure(t *testing.T) {
+	s, err := suite.Parse([]byte(`
+scenarios:
+  - name: failing
+    steps:
+      - operation: create
+        type: item
+        input:
+          name: widget
+        capture:
+          id: $.external_id
+        expect:
+          - path: $.properties.name
+            equals: gadget
+          - path: $.properties.size
+      - operation: read
+        type: item
+        external_id: ${id}
+      - name: cleanup
+        operation: delete
+        type: item
+        external_id: ${id}
+        always: true
+  - name: undefined variable
+    steps:
+      - operation: read
+        type: item
+        external_id: ${missing}
+`))
+	require.NoError(t, err)
+
+	result := suite.Run(context.Background(), newTestClient(t), s, suite.Options{})
+	require.True(t, result.Failed())
+
+	steps := result.Scenarios[0].Steps
+	assert.Equal(t, []string{
+		`$.properties.name: expected "gadget", got "widget"`,
+		"$.properties.size: expected a value",
+	}, steps[0].Failures)
+	assert.True(t, steps[1].Skipped)
+	assert.Equal(t, "cleanup", steps[2].Name)
+	assert.False(t, steps[2].Skipped)
+	assert.False(t, steps[2].Failed())
+
+	assert.EqualError(t, result.Scenarios[1].Steps[0].Err, "external_id: undefined variable missing")
+
+	passed, failed, skipped := result.Counts()
+	assert.Equal(t, 1, passed)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, 1, skipped)
+}
+
+// newPagingClient starts an app listing one item per page, with the next page
+// token returned by next, and returns a client connected to it.
+func newPagingClient(t *testing.T, next func(token string) string) appv1connect.AppServiceClient {
+	t.Helper()
+
+	rd := app.ResourceDefinition{
+		Type:             "item",
+		DisplayName:      "Item",
+		PropertiesSchema: app.MustParseJSONSchema(itemSchema),
+	}
+	rd.ListFn(func(ctx context.Context, req *app.ListRequest) (*app.ListResponse, error) {
+		return &app.ListResponse{
+			Resources: []*app.Resource{{ExternalID: "item", DisplayName: "Item", Type: "item", Properties: map[string]any{"name": "item"}}},
+			Next:      next(req.Next),
+		}, nil
+	})
+
+	a := app.New(app.WithResourceDefinition(rd))
+
+	mux := http.NewServeMux()
+	mux.Handle(appv1connect.AppServiceListResourcesProcedure, connect.NewUnaryHandler(appv1connect.AppServiceListResourcesProcedure, a.ListResources))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return appv1connect.NewAppServiceClient(srv.Client(), srv.URL)
+}
+
+func TestRunListPaging(t *testing.T) {
+	s, err := suite.Parse([]byte(`
+scenarios:
+  - name: list
+    steps:
+      - operation: list
+        type: item
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		next func(token string) string
+		err  string
+	}{
+		{
+			name: "repeated token",
+			next: func(token string) string { return "again" },
+			err:  `the app returned the next page token "again" of the page it was given`,
+		},
+		{
+			name: "endless pages",
+			next: func(token string) string { return token + "x" },
+			err:  "the app returned more than 100 pages",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := suite.Run(context.Background(), newPagingClient(t, tt.next), s, suite.Options{})
+			require.True(t, result.Failed())
+			assert.EqualError(t, result.Scenarios[0].Steps[0].Err, tt.err)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite string
+		err   string
+	}{
+		{
+			name:  "no scenarios",
+			suite: `scenarios: []`,
+			err:   "suite has no scenarios",
+		},
+		{
+			name: "invalid operation",
+			suite: `
+scenarios:
+  - name: s
+    steps:
+      - operation: destroy
+        type: item`,
+			err: `s: step 1: invalid operation "destroy". Accepted values: 'create', 'read', 'update', 'delete', 'list'`,
+		},
+		{
+			name: "missing external ID",
+			suite: `
+scenarios:
+  - name: s
+    steps:
+      - operation: read
+        type: item`,
+			err: "s: step 1: external_id is required by the read operation",
+		},
+		{
+			name: "invalid snapshot ignore path",
+			suite: `
+snapshot_ignore: [external_id]
+scenarios:
+  - name: s
+    steps:
+      - operation: list
+        type: item`,
+			err: `snapshot_ignore: invalid ignore path "external_id": must start with $`,
+		},
+		{
+			name: "invalid path",
+			suite: `
+scenarios:
+  - name: s
+    steps:
+      - operation: list
+        type: item
+        expect:
+          - path: resources`,
+			err: `s: step 1: expect: invalid path "resources": must start with $`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := suite.Parse([]byte(tt.suite))
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestRunPropertiesSchema(t *testing.T) {
+	s, err := suite.Parse([]byte(`
+scenarios:
+  - name: properties
+    steps:
+      - operation: create
+        type: item
+        input:
+          name: widget
+      - operation: list
+        type: item
+`))
+	require.NoError(t, err)
+
+	sch, err := schema.New(map[string]any{
+		"type":     "object",
+		"required": []any{"color"},
+	})
+	require.NoError(t, err)
+
+	result := suite.Run(context.Background(), newTestClient(t), s, suite.Options{
+		PropertiesSchemas: map[string]*schema.Schema{"item": sch},
+	})
+
+	steps := result.Scenarios[0].Steps
+	assert.Equal(t, []string{"$.properties: does not match the properties schema: /color: is required"}, steps[0].Failures)
+	assert.True(t, steps[1].Skipped)
+}
+
+func TestRunSnapshots(t *testing.T) {
+	const suiteYAML = `
+vars:
+  size: %d
+snapshot_ignore:
+  - $..url
+scenarios:
+  - name: Two items
+    steps:
+      - operation: create
+        type: item
+        input:
+          name: first
+          size: ${size}
+      - operation: create
+        type: item
+        input:
+          name: second
+          size: 1
+`
+	store := &snapshot.Store{
+		Dir:    t.TempDir(),
+		Ignore: []string{"$.external_id"},
+	}
+
+	// Each run creates items with new external IDs and URLs, which are
+	// ignored.
+	client := newTestClient(t)
+	run := func(size int) *suite.Result {
+		s, err := suite.Parse(fmt.Appendf(nil, suiteYAML, size))
+		require.NoError(t, err)
+
+		return suite.Run(context.Background(), client, s, suite.Options{Snapshots: store})
+	}
+
+	result := run(2)
+	require.False(t, result.Failed())
+	steps := result.Scenarios[0].Steps
+	assert.Equal(t, snapshot.StatusCreated, steps[0].Snapshot.Status)
+	assert.Equal(t, filepath.Join(store.Dir, "two-items", "create-item.json"), steps[0].Snapshot.Path)
+	assert.Equal(t, filepath.Join(store.Dir, "two-items", "create-item-2.json"), steps[1].Snapshot.Path)
+
+	b, err := os.ReadFile(steps[0].Snapshot.Path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"external_id": "<ignored>"`)
+	assert.Contains(t, string(b), `"url": "<ignored>"`)
+
+	result = run(2)
+	require.False(t, result.Failed())
+	assert.Equal(t, snapshot.StatusMatched, result.Scenarios[0].Steps[0].Snapshot.Status)
+
+	result = run(3)
+	steps = result.Scenarios[0].Steps
+	require.Len(t, steps[0].Failures, 1)
+	assert.Contains(t, steps[0].Failures[0], "the response does not match the snapshot")
+	assert.Contains(t, steps[0].Failures[0], "-    \"size\": 2\n+    \"size\": 3\n")
+	assert.True(t, steps[1].Skipped)
+	assert.Equal(t, snapshot.StatusMismatch, result.Summary().Scenarios[0].Steps[0].Snapshot)
+
+	store.Update = true
+	result = run(3)
+	require.False(t, result.Failed())
+	assert.Equal(t, snapshot.StatusUpdated, result.Scenarios[0].Steps[0].Snapshot.Status)
+}
@@ -0,0 +1,206 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/tempestdx/cli/internal/schema"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// checkHandlers checks that the app has a handler for each operation declared
+// as supported. Create, read, update and delete are probed with requests the
+// app must reject before calling the handler: a create input which does not
+// match the input schema, or no external ID. A resource created by the create
+// probe all the same is deleted. List and health checks have no side effects,
+// and are called.
+func (v *verifier) checkHandlers(ctx context.Context, rd *appv1.ResourceDefinition, schemas *checkedSchemas) {
+	if rd.CreateSupported {
+		v.probeCreate(ctx, rd, schemas.createInput)
+	}
+
+	probes := []struct {
+		supported bool
+		operation appv1.ResourceOperation
+		name      string
+	}{
+		{rd.ReadSupported, appv1.ResourceOperation_RESOURCE_OPERATION_READ, "read"},
+		{rd.UpdateSupported, appv1.ResourceOperation_RESOURCE_OPERATION_UPDATE, "update"},
+		{rd.DeleteSupported, appv1.ResourceOperation_RESOURCE_OPERATION_DELETE, "delete"},
+	}
+	for _, p := range probes {
+		if !p.supported {
+			continue
+		}
+
+		_, err := v.client.ExecuteResourceOperation(ctx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+			Operation: p.operation,
+			Resource: &appv1.Resource{
+				Type: rd.Type,
+			},
+			Input:                &structpb.Struct{},
+			Metadata:             v.opts.Metadata,
+			EnvironmentVariables: v.opts.Env,
+		}))
+		switch {
+		case notImplemented(err):
+			v.add(SeverityError, rd.Type, CheckHandlers, "%s is declared as supported, but the app has no %s handler: %s", p.name, p.name, err)
+		case err == nil:
+			v.add(SeverityWarning, rd.Type, CheckHandlers, "%s succeeded without an external ID", p.name)
+		}
+	}
+
+	if rd.ListSupported {
+		res, err := v.client.ListResources(ctx, connect.NewRequest(&appv1.ListResourcesRequest{
+			Resource: &appv1.Resource{
+				Type: rd.Type,
+			},
+			Metadata: v.opts.Metadata,
+		}))
+		switch {
+		case notImplemented(err):
+			v.add(SeverityError, rd.Type, CheckHandlers, "list is declared as supported, but the app has no list handler: %s", err)
+		case err != nil:
+			v.add(SeverityError, rd.Type, CheckHandlers, "list failed: %s", err)
+		default:
+			v.checkProperties(rd.Type, CheckHandlers, "list", schemas.properties, res.Msg.Resources...)
+		}
+	}
+
+	if rd.HealthcheckSupported {
+		res, err := v.client.HealthCheck(ctx, connect.NewRequest(&appv1.HealthCheckRequest{
+			Type: rd.Type,
+		}))
+		switch {
+		case notImplemented(err):
+			v.add(SeverityError, rd.Type, CheckHandlers, "health check is declared as supported, but the app has no health check handler: %s", err)
+		case err != nil:
+			v.add(SeverityError, rd.Type, CheckHandlers, "health check failed: %s", err)
+		case res.Msg.Status != appv1.HealthCheckStatus_HEALTH_CHECK_STATUS_HEALTHY:
+			v.add(SeverityWarning, rd.Type, CheckHandlers, "health check status is %s: %s", strings.ToLower(strings.TrimPrefix(res.Msg.Status.String(), "HEALTH_CHECK_STATUS_")), res.Msg.Message)
+		}
+	}
+}
+
+// probeCreate sends a create input which does not match the input schema.
+func (v *verifier) probeCreate(ctx context.Context, rd *appv1.ResourceDefinition, inputSchema *schema.Schema) {
+	if inputSchema == nil {
+		v.add(SeverityInfo, rd.Type, CheckHandlers, "the create handler was not probed: the create input schema is empty or invalid")
+		return
+	}
+
+	input := invalidInput(inputSchema)
+	if input == nil {
+		v.add(SeverityInfo, rd.Type, CheckHandlers, "the create handler was not probed: every input matches the create input schema")
+		return
+	}
+
+	in, err := structpb.NewStruct(input)
+	if err != nil {
+		v.add(SeverityInfo, rd.Type, CheckHandlers, "the create handler was not probed: %s", err)
+		return
+	}
+
+	res, err := v.client.ExecuteResourceOperation(ctx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+		Operation: appv1.ResourceOperation_RESOURCE_OPERATION_CREATE,
+		Resource: &appv1.Resource{
+			Type: rd.Type,
+		},
+		Input:                in,
+		Metadata:             v.opts.Metadata,
+		EnvironmentVariables: v.opts.Env,
+	}))
+	switch {
+	case notImplemented(err):
+		v.add(SeverityError, rd.Type, CheckHandlers, "create is declared as supported, but the app has no create handler: %s", err)
+	case err == nil:
+		id := res.Msg.GetResource().GetExternalId()
+		v.add(SeverityError, rd.Type, CheckHandlers, "create accepted an input which does not match the create input schema, and created resource %s", id)
+		v.deleteProbed(ctx, rd, id)
+	case connect.CodeOf(err) != connect.CodeInvalidArgument:
+		v.add(SeverityWarning, rd.Type, CheckHandlers, "create rejected an input which does not match the create input schema with code %s, want invalid_argument: %s", connect.CodeOf(err), err)
+	}
+}
+
+// deleteProbed deletes the resource created by the create probe, which must
+// not leave resources behind, or reports it when it cannot.
+func (v *verifier) deleteProbed(ctx context.Context, rd *appv1.ResourceDefinition, id string) {
+	switch {
+	case !rd.DeleteSupported:
+		v.add(SeverityWarning, rd.Type, CheckHandlers, "delete is not supported: resource %s created by the create probe was left in place, and must be cleaned up", id)
+	case id == "":
+		v.add(SeverityWarning, rd.Type, CheckHandlers, "the resource created by the create probe has no external ID, and must be cleaned up")
+	default:
+		if _, err := v.operation(ctx, appv1.ResourceOperation_RESOURCE_OPERATION_DELETE, &appv1.Resource{Type: rd.Type, ExternalId: id}, nil); err != nil {
+			v.add(SeverityWarning, rd.Type, CheckHandlers, "delete of resource %s created by the create probe failed, and it must be cleaned up: %s", id, err)
+		}
+	}
+}
+
+// invalidInput returns an input which does not match the schema, or nil when
+// every input tried matches it.
+func invalidInput(s *schema.Schema) map[string]any {
+	var candidates []map[string]any
+
+	properties, _ := s.Raw()["properties"].(map[string]any)
+	// Apps fill in missing properties with their defaults before validating
+	// the input, so an empty input is only invalid without defaults.
+	if !slices.ContainsFunc(slices.Collect(maps.Values(properties)), func(p any) bool {
+		prop, _ := p.(map[string]any)
+		_, ok := prop["default"]
+		return ok
+	}) {
+		candidates = append(candidates, map[string]any{})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(properties)) {
+		p, _ := properties[name].(map[string]any)
+		// A boolean matches anything but booleans and untyped properties.
+		var wrong any = true
+		if t, _ := p["type"].(string); t == "boolean" || t == "" {
+			wrong = []any{}
+		}
+		candidates = append(candidates, map[string]any{name: wrong})
+	}
+	candidates = append(candidates, map[string]any{"tempest_verify_unknown_property": true})
+
+	for _, c := range candidates {
+		if s.Validate(c) != nil {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// notImplemented reports whether err is the response of an app without a
+// handler for the request.
+func notImplemented(err error) bool {
+	var ce *connect.Error
+	if !errors.As(err, &ce) {
+		return false
+	}
+
+	return ce.Code() == connect.CodeUnimplemented ||
+		ce.Code() == connect.CodeInvalidArgument && strings.Contains(ce.Message(), "not supported")
+}
+
+// checkProperties reports the resources returned by operation whose
+// properties do not match the properties schema.
+func (v *verifier) checkProperties(typ, check, operation string, propertiesSchema *schema.Schema, resources ...*appv1.Resource) {
+	if propertiesSchema == nil {
+		return
+	}
+
+	for _, r := range resources {
+		err := propertiesSchema.Validate(r.GetProperties().AsMap())
+		if err != nil {
+			v.add(SeverityError, typ, check, "%s returned resource %s with properties which do not match the properties schema: %s", operation, r.GetExternalId(), err)
+		}
+	}
+}
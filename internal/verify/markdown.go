@@ -0,0 +1,115 @@
+package verify
+
+import (
+	"net/url"
+	"strings"
+
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// checkInstructions checks that the instructions of the resource definition
+// are well-formed markdown: code fences are closed, and links and images
+// have absolute destinations.
+func (v *verifier) checkInstructions(rd *appv1.ResourceDefinition) {
+	if strings.TrimSpace(rd.InstructionsMarkdown) == "" {
+		v.add(SeverityInfo, rd.Type, CheckInstructions, "the resource definition has no instructions")
+		return
+	}
+
+	if line := unclosedFence(rd.InstructionsMarkdown); line > 0 {
+		v.add(SeverityError, rd.Type, CheckInstructions, "the code fence opened on line %d is never closed", line)
+	}
+
+	src := []byte(rd.InstructionsMarkdown)
+	doc := goldmark.New().Parser().Parse(text.NewReader(src))
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var kind string
+		var destination []byte
+		switch n := n.(type) {
+		case *ast.Link:
+			kind, destination = "link", n.Destination
+		case *ast.Image:
+			kind, destination = "image", n.Destination
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		label := plainText(n, src)
+		dest := string(destination)
+		u, err := url.Parse(dest)
+		switch {
+		case dest == "":
+			v.add(SeverityError, rd.Type, CheckInstructions, "%s %q has no destination", kind, label)
+		case err != nil:
+			v.add(SeverityError, rd.Type, CheckInstructions, "%s %q has an invalid destination: %s", kind, label, err)
+		case strings.HasPrefix(dest, "#"):
+		case !u.IsAbs():
+			v.add(SeverityWarning, rd.Type, CheckInstructions, "%s %q destination %s is relative, and cannot be resolved by Tempest", kind, label, dest)
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// plainText returns the text of the children of n, without formatting.
+func plainText(n ast.Node, src []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			b.Write(t.Segment.Value(src))
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return b.String()
+}
+
+// unclosedFence returns the line of the code fence left open at the end of the
+// markdown, or 0 when all fences are closed.
+func unclosedFence(markdown string) int {
+	var open string
+	var openLine int
+
+	for i, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) > 3 {
+			continue
+		}
+
+		fence := fenceOf(trimmed)
+		switch {
+		case fence == "":
+		case open == "":
+			open, openLine = fence, i+1
+		case fence[0] == open[0] && len(fence) >= len(open) && strings.TrimSpace(trimmed[len(fence):]) == "":
+			open = ""
+		}
+	}
+
+	if open == "" {
+		return 0
+	}
+
+	return openLine
+}
+
+// fenceOf returns the code fence starting line: three or more backticks or
+// tildes.
+func fenceOf(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,145 @@
+package verify
+
+import (
+	"context"
+	"reflect"
+
+	"connectrpc.com/connect"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxRoundTripPages is the number of list pages searched for the resource
+// created by the round trip.
+const maxRoundTripPages = 100
+
+// roundTrip creates a resource, then reads it, lists it and deletes it, when
+// the operations are supported, and checks that the app reports the same
+// resource throughout.
+func (v *verifier) roundTrip(ctx context.Context, rd *appv1.ResourceDefinition, schemas *checkedSchemas) {
+	if !rd.CreateSupported {
+		v.add(SeverityInfo, rd.Type, CheckRoundTrip, "the round trip was skipped: create is not supported")
+		return
+	}
+
+	input, ok := v.opts.Inputs[rd.Type]
+	if !ok {
+		if schemas.createInput == nil {
+			v.add(SeverityWarning, rd.Type, CheckRoundTrip, "the round trip was skipped: the create input schema is empty or invalid. Pass the input to create with --input")
+			return
+		}
+
+		var err error
+		input, err = schemas.createInput.Example()
+		if err != nil {
+			v.add(SeverityWarning, rd.Type, CheckRoundTrip, "the round trip was skipped: no create input could be generated: %s. Pass the input to create with --input", err)
+			return
+		}
+	}
+
+	in, err := structpb.NewStruct(input)
+	if err != nil {
+		v.add(SeverityError, rd.Type, CheckRoundTrip, "invalid create input: %s", err)
+		return
+	}
+
+	created, err := v.operation(ctx, appv1.ResourceOperation_RESOURCE_OPERATION_CREATE, &appv1.Resource{Type: rd.Type}, in)
+	if err != nil {
+		v.add(SeverityError, rd.Type, CheckRoundTrip, "create failed: %s", err)
+		return
+	}
+	if created.GetExternalId() == "" {
+		v.add(SeverityError, rd.Type, CheckRoundTrip, "create returned a resource without an external ID")
+		return
+	}
+	id := created.ExternalId
+	v.checkProperties(rd.Type, CheckRoundTrip, "create", schemas.properties, created)
+
+	ref := &appv1.Resource{Type: rd.Type, ExternalId: id}
+
+	if rd.ReadSupported {
+		read, err := v.operation(ctx, appv1.ResourceOperation_RESOURCE_OPERATION_READ, ref, nil)
+		switch {
+		case err != nil:
+			v.add(SeverityError, rd.Type, CheckRoundTrip, "read of the created resource %s failed: %s", id, err)
+		case read.GetExternalId() != id:
+			v.add(SeverityError, rd.Type, CheckRoundTrip, "read of the created resource %s returned resource %q", id, read.GetExternalId())
+		default:
+			v.checkProperties(rd.Type, CheckRoundTrip, "read", schemas.properties, read)
+			if !reflect.DeepEqual(read.GetProperties().AsMap(), created.GetProperties().AsMap()) {
+				v.add(SeverityWarning, rd.Type, CheckRoundTrip, "read returned different properties for resource %s than create", id)
+			}
+			if read.DisplayName != created.DisplayName {
+				v.add(SeverityWarning, rd.Type, CheckRoundTrip, "read returned display name %q for resource %s, create returned %q", read.DisplayName, id, created.DisplayName)
+			}
+		}
+	}
+
+	if rd.ListSupported {
+		v.findListed(ctx, rd.Type, id)
+	}
+
+	if !rd.DeleteSupported {
+		v.add(SeverityWarning, rd.Type, CheckRoundTrip, "delete is not supported: resource %s created by the round trip was left in place", id)
+		return
+	}
+
+	if _, err := v.operation(ctx, appv1.ResourceOperation_RESOURCE_OPERATION_DELETE, ref, nil); err != nil {
+		v.add(SeverityError, rd.Type, CheckRoundTrip, "delete of the created resource %s failed: %s", id, err)
+		return
+	}
+
+	if rd.ReadSupported {
+		if _, err := v.operation(ctx, appv1.ResourceOperation_RESOURCE_OPERATION_READ, ref, nil); err == nil {
+			v.add(SeverityWarning, rd.Type, CheckRoundTrip, "resource %s can still be read after it was deleted", id)
+		}
+	}
+}
+
+// findListed pages through the list of resources of the type, and reports
+// when the resource id is missing.
+func (v *verifier) findListed(ctx context.Context, typ, id string) {
+	var next string
+	for range maxRoundTripPages {
+		res, err := v.client.ListResources(ctx, connect.NewRequest(&appv1.ListResourcesRequest{
+			Resource: &appv1.Resource{
+				Type: typ,
+			},
+			Metadata: v.opts.Metadata,
+			Next:     next,
+		}))
+		if err != nil {
+			v.add(SeverityError, typ, CheckRoundTrip, "list failed: %s", err)
+			return
+		}
+
+		for _, r := range res.Msg.Resources {
+			if r.ExternalId == id {
+				return
+			}
+		}
+
+		next = res.Msg.Next
+		if next == "" {
+			v.add(SeverityError, typ, CheckRoundTrip, "the created resource %s is missing from the list of resources", id)
+			return
+		}
+	}
+
+	v.add(SeverityInfo, typ, CheckRoundTrip, "the created resource %s was not found in the first %d pages of the list of resources", id, maxRoundTripPages)
+}
+
+func (v *verifier) operation(ctx context.Context, operation appv1.ResourceOperation, resource *appv1.Resource, input *structpb.Struct) (*appv1.Resource, error) {
+	res, err := v.client.ExecuteResourceOperation(ctx, connect.NewRequest(&appv1.ExecuteResourceOperationRequest{
+		Operation:            operation,
+		Resource:             resource,
+		Input:                input,
+		Metadata:             v.opts.Metadata,
+		EnvironmentVariables: v.opts.Env,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Msg.Resource, nil
+}
@@ -0,0 +1,257 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/tempestdx/cli/internal/schema"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The $schema of the schemas declared by apps, as used by the app templates.
+const (
+	AppSchemaURL        = "https://developer.tempestdx.com/schema/v1/tempest-app-schema.json"
+	PropertiesSchemaURL = "https://developer.tempestdx.com/schema/v1/tempest-properties-schema.json"
+)
+
+// Checks reported in findings.
+const (
+	CheckDefinition   = "definition"
+	CheckHandlers     = "handlers"
+	CheckSchemas      = "schemas"
+	CheckLinks        = "links"
+	CheckInstructions = "instructions"
+	CheckRoundTrip    = "round trip"
+)
+
+// Severity is how much a finding breaks the contract of the app.
+type Severity int
+
+const (
+	// SeverityInfo findings are only reported.
+	SeverityInfo Severity = iota
+	// SeverityWarning findings are likely mistakes, which Tempest tolerates.
+	SeverityWarning
+	// SeverityError findings break the contract Tempest relies on.
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity parses the name of a severity: info, warning or error.
+func ParseSeverity(name string) (Severity, error) {
+	i := slices.Index(severityNames, name)
+	if i == -1 {
+		return 0, fmt.Errorf("invalid severity %q. Accepted values: %s", name, strings.Join(severityNames, ", "))
+	}
+
+	return Severity(i), nil
+}
+
+// Finding is a problem found in the app.
+type Finding struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	// The resource type the finding is about.
+	Type    string `json:"type" yaml:"type"`
+	Check   string `json:"check" yaml:"check"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Options configure the requests sent to the app.
+type Options struct {
+	Metadata *appv1.Metadata
+	Env      []*appv1.EnvironmentVariable
+	// RoundTrip creates a resource of each type supporting create, then
+	// reads, lists and deletes it, when supported.
+	RoundTrip bool
+	// Inputs are the inputs of the round trip creates, by type. Types without
+	// an input use an example of their create input schema.
+	Inputs map[string]map[string]any
+}
+
+type verifier struct {
+	client   appv1connect.AppServiceClient
+	opts     Options
+	findings []Finding
+}
+
+func (v *verifier) add(severity Severity, typ, check, format string, args ...any) {
+	v.findings = append(v.findings, Finding{
+		Severity: severity,
+		Type:     typ,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Run describes the app, and checks the contract Tempest relies on for each
+// resource definition. Probes sent to check the handlers are rejected by the
+// app before reaching them, so only the round trip changes resources. An
+// error is returned when the app cannot be described.
+func Run(ctx context.Context, client appv1connect.AppServiceClient, opts Options) ([]Finding, error) {
+	des, err := client.Describe(ctx, connect.NewRequest(&appv1.DescribeRequest{}))
+	if err != nil {
+		return nil, fmt.Errorf("describe app: %w", err)
+	}
+
+	v := &verifier{
+		client: client,
+		opts:   opts,
+	}
+
+	counts := make(map[string]int)
+	for _, rd := range des.Msg.ResourceDefinitions {
+		counts[rd.Type]++
+	}
+
+	if len(des.Msg.ResourceDefinitions) == 0 {
+		v.add(SeverityError, "", CheckDefinition, "the app declares no resource definitions")
+	}
+
+	seen := make(map[string]bool)
+	for _, rd := range des.Msg.ResourceDefinitions {
+		if rd.Type == "" {
+			v.add(SeverityError, rd.Type, CheckDefinition, "resource definition %q has no type", rd.DisplayName)
+			continue
+		}
+
+		// Duplicated types are reported once, and not checked further.
+		if seen[rd.Type] {
+			continue
+		}
+		seen[rd.Type] = true
+		if n := counts[rd.Type]; n > 1 {
+			v.add(SeverityError, rd.Type, CheckDefinition, "the type is declared by %d resource definitions, types must be unique", n)
+			continue
+		}
+
+		v.checkDefinition(rd)
+		v.checkLinks(rd)
+		v.checkInstructions(rd)
+		schemas := v.checkSchemas(rd)
+		v.checkHandlers(ctx, rd, schemas)
+
+		if opts.RoundTrip {
+			v.roundTrip(ctx, rd, schemas)
+		}
+	}
+
+	return v.findings, nil
+}
+
+func (v *verifier) checkDefinition(rd *appv1.ResourceDefinition) {
+	if rd.DisplayName == "" {
+		v.add(SeverityWarning, rd.Type, CheckDefinition, "the resource definition has no display name")
+	}
+
+	if rd.LifecycleStage == appv1.LifecycleStage_LIFECYCLE_STAGE_UNSPECIFIED {
+		v.add(SeverityInfo, rd.Type, CheckDefinition, "the resource definition has no lifecycle stage")
+	}
+
+	names := make(map[string]bool)
+	for _, a := range rd.Actions {
+		switch {
+		case a.Name == "":
+			v.add(SeverityError, rd.Type, CheckDefinition, "action %q has no name", a.DisplayName)
+		case names[a.Name]:
+			v.add(SeverityError, rd.Type, CheckDefinition, "action %s is declared more than once, action names must be unique", a.Name)
+		}
+		names[a.Name] = true
+	}
+}
+
+// linkTypes are the names of the link types accepted by Tempest.
+var linkTypes = []string{"documentation", "administration", "support", "endpoint", "external"}
+
+func (v *verifier) checkLinks(rd *appv1.ResourceDefinition) {
+	for i, l := range rd.Links {
+		name := l.Title
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+			v.add(SeverityWarning, rd.Type, CheckLinks, "link %s has no title", name)
+		}
+
+		if _, ok := appv1.LinkType_name[int32(l.Type)]; !ok {
+			v.add(SeverityError, rd.Type, CheckLinks, "link %s has an unknown type %d. Accepted types: %s", name, l.Type, strings.Join(linkTypes, ", "))
+		} else if l.Type == appv1.LinkType_LINK_TYPE_UNSPECIFIED {
+			v.add(SeverityError, rd.Type, CheckLinks, "link %s has no type. Accepted types: %s", name, strings.Join(linkTypes, ", "))
+		}
+
+		u, err := url.Parse(l.Url)
+		switch {
+		case l.Url == "":
+			v.add(SeverityError, rd.Type, CheckLinks, "link %s has no URL", name)
+		case err != nil:
+			v.add(SeverityError, rd.Type, CheckLinks, "link %s has an invalid URL: %s", name, err)
+		case u.Scheme != "http" && u.Scheme != "https" || u.Host == "":
+			v.add(SeverityError, rd.Type, CheckLinks, "link %s URL %s is not an absolute http or https URL", name, l.Url)
+		}
+	}
+}
+
+// checkedSchemas are the schemas of a resource definition which compiled.
+type checkedSchemas struct {
+	properties  *schema.Schema
+	createInput *schema.Schema
+}
+
+// checkSchemas checks the $schema of the schemas of the resource definition,
+// and that they are valid draft 2020-12 schemas.
+func (v *verifier) checkSchemas(rd *appv1.ResourceDefinition) *checkedSchemas {
+	schemas := &checkedSchemas{}
+	schemas.properties = v.checkSchema(rd.Type, "properties schema", rd.PropertiesSchema, PropertiesSchemaURL)
+	if rd.CreateSupported {
+		schemas.createInput = v.checkSchema(rd.Type, "create input schema", rd.CreateInputSchema, AppSchemaURL)
+	}
+	if rd.UpdateSupported {
+		v.checkSchema(rd.Type, "update input schema", rd.UpdateInputSchema, AppSchemaURL)
+	}
+	for _, a := range rd.Actions {
+		v.checkSchema(rd.Type, fmt.Sprintf("action %s input schema", a.Name), a.InputSchema, AppSchemaURL)
+		v.checkSchema(rd.Type, fmt.Sprintf("action %s output schema", a.Name), a.OutputSchema, AppSchemaURL)
+	}
+
+	return schemas
+}
+
+func (v *verifier) checkSchema(typ, name string, s *structpb.Struct, schemaURL string) *schema.Schema {
+	if len(s.GetFields()) == 0 {
+		v.add(SeverityWarning, typ, CheckSchemas, "the %s is empty", name)
+		return nil
+	}
+
+	raw := s.AsMap()
+	switch declared, _ := raw["$schema"].(string); declared {
+	case schemaURL:
+	case "":
+		v.add(SeverityWarning, typ, CheckSchemas, "the %s has no $schema. Set it to %s", name, schemaURL)
+	default:
+		v.add(SeverityWarning, typ, CheckSchemas, "the %s $schema is %s, want %s", name, declared, schemaURL)
+	}
+
+	sch, err := schema.New(raw)
+	if err != nil {
+		v.add(SeverityError, typ, CheckSchemas, "the %s is invalid: %s", name, err)
+		return nil
+	}
+
+	return sch
+}
@@ -0,0 +1,270 @@
+package verify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tempestdx/cli/internal/verify"
+	appv1 "github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1"
+	"github.com/tempestdx/protobuf/gen/go/tempestdx/app/v1/appv1connect"
+	"github.com/tempestdx/sdk-go/app"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// describeOnly is an app which only implements Describe, so every operation
+// it declares as supported lacks a handler.
+type describeOnly struct {
+	appv1connect.UnimplementedAppServiceHandler
+	definitions []*appv1.ResourceDefinition
+}
+
+func (d *describeOnly) Describe(context.Context, *connect.Request[appv1.DescribeRequest]) (*connect.Response[appv1.DescribeResponse], error) {
+	return connect.NewResponse(&appv1.DescribeResponse{ResourceDefinitions: d.definitions}), nil
+}
+
+func newClient(t *testing.T, h appv1connect.AppServiceHandler) appv1connect.AppServiceClient {
+	t.Helper()
+
+	_, handler := appv1connect.NewAppServiceHandler(h)
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return appv1connect.NewAppServiceClient(srv.Client(), srv.URL)
+}
+
+func mustStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+
+	s, err := structpb.NewStruct(m)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestRunContract(t *testing.T) {
+	client := newClient(t, &describeOnly{definitions: []*appv1.ResourceDefinition{
+		{
+			Type:           "bucket",
+			DisplayName:    "Bucket",
+			LifecycleStage: appv1.LifecycleStage_LIFECYCLE_STAGE_DEPLOY,
+			ReadSupported:  true,
+			PropertiesSchema: mustStruct(t, map[string]any{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type":    "object",
+			}),
+			Links: []*appv1.Link{
+				{Title: "Docs", Url: "https://example.com/docs", Type: appv1.LinkType_LINK_TYPE_DOCUMENTATION},
+				{Title: "Console", Url: "/console", Type: appv1.LinkType(42)},
+				{Url: "https://example.com"},
+			},
+			InstructionsMarkdown: "# Bucket\n\nSee [the guide](guide.md) and [nothing]().\n\n```sh\nmake bucket\n",
+		},
+		{
+			Type:             "queue",
+			DisplayName:      "Queue",
+			LifecycleStage:   appv1.LifecycleStage_LIFECYCLE_STAGE_DEPLOY,
+			CreateSupported:  true,
+			PropertiesSchema: mustStruct(t, map[string]any{"$schema": verify.PropertiesSchemaURL, "type": "object"}),
+			CreateInputSchema: mustStruct(t, map[string]any{
+				"$schema":    verify.AppSchemaURL,
+				"type":       "object",
+				"properties": map[string]any{"size": map[string]any{"type": "integer", "minimum": "1"}},
+			}),
+			InstructionsMarkdown: "Queues are [documented](https://example.com/queues).",
+		},
+		{Type: "topic", DisplayName: "Topic"},
+		{Type: "topic", DisplayName: "Topic again"},
+	}})
+
+	findings, err := verify.Run(context.Background(), client, verify.Options{})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, fmt.Sprintf("%s %s %s: %s", f.Severity, f.Type, f.Check, f.Message))
+	}
+
+	assert.Equal(t, []string{
+		"error bucket links: link Console has an unknown type 42. Accepted types: documentation, administration, support, endpoint, external",
+		"error bucket links: link Console URL /console is not an absolute http or https URL",
+		"warning bucket links: link 3 has no title",
+		"error bucket links: link 3 has no type. Accepted types: documentation, administration, support, endpoint, external",
+		"error bucket instructions: the code fence opened on line 5 is never closed",
+		`warning bucket instructions: link "the guide" destination guide.md is relative, and cannot be resolved by Tempest`,
+		`error bucket instructions: link "nothing" has no destination`,
+		"warning bucket schemas: the properties schema $schema is http://json-schema.org/draft-07/schema#, want " + verify.PropertiesSchemaURL,
+		"error bucket handlers: read is declared as supported, but the app has no read handler: unimplemented: tempestdx.app.v1.AppService.ExecuteResourceOperation is not implemented",
+		"error queue schemas: the create input schema is invalid: not a valid draft 2020-12 JSON schema: /properties/size/minimum: got string, want number",
+		"info queue handlers: the create handler was not probed: the create input schema is empty or invalid",
+		"error topic definition: the type is declared by 2 resource definitions, types must be unique",
+	}, got)
+}
+
+// lenientApp creates resources whatever their input, and records the
+// resources it deletes.
+type lenientApp struct {
+	describeOnly
+	deleted []string
+}
+
+func (l *lenientApp) ExecuteResourceOperation(_ context.Context, req *connect.Request[appv1.ExecuteResourceOperationRequest]) (*connect.Response[appv1.ExecuteResourceOperationResponse], error) {
+	switch req.Msg.Operation {
+	case appv1.ResourceOperation_RESOURCE_OPERATION_CREATE:
+		return connect.NewResponse(&appv1.ExecuteResourceOperationResponse{Resource: &appv1.Resource{Type: "queue", ExternalId: "queue-1"}}), nil
+	case appv1.ResourceOperation_RESOURCE_OPERATION_DELETE:
+		if req.Msg.Resource.ExternalId == "" {
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("missing external ID"))
+		}
+		l.deleted = append(l.deleted, req.Msg.Resource.ExternalId)
+		return connect.NewResponse(&appv1.ExecuteResourceOperationResponse{Resource: req.Msg.Resource}), nil
+	}
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("not implemented"))
+}
+
+func TestRunDeletesProbedResource(t *testing.T) {
+	rd := &appv1.ResourceDefinition{
+		Type:             "queue",
+		DisplayName:      "Queue",
+		LifecycleStage:   appv1.LifecycleStage_LIFECYCLE_STAGE_DEPLOY,
+		CreateSupported:  true,
+		DeleteSupported:  true,
+		PropertiesSchema: mustStruct(t, map[string]any{"$schema": verify.PropertiesSchemaURL, "type": "object"}),
+		CreateInputSchema: mustStruct(t, map[string]any{
+			"$schema":  verify.AppSchemaURL,
+			"type":     "object",
+			"required": []any{"name"},
+		}),
+	}
+	a := &lenientApp{describeOnly: describeOnly{definitions: []*appv1.ResourceDefinition{rd}}}
+
+	findings, err := verify.Run(context.Background(), newClient(t, a), verify.Options{})
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range findings {
+		if f.Check == verify.CheckHandlers {
+			got = append(got, fmt.Sprintf("%s: %s", f.Severity, f.Message))
+		}
+	}
+	assert.Equal(t, []string{
+		"error: create accepted an input which does not match the create input schema, and created resource queue-1",
+	}, got)
+	assert.Equal(t, []string{"queue-1"}, a.deleted)
+}
+
+var itemSchema = []byte(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 3},
+		"size": {"type": "integer", "minimum": 1}
+	},
+	"required": ["name"],
+	"additionalProperties": false
+}`)
+
+// newItemApp returns an app managing items in memory. When hideFromList is
+// set, created items are missing from the list.
+func newItemApp(t *testing.T, hideFromList bool) (appv1connect.AppServiceClient, map[string]map[string]any) {
+	t.Helper()
+
+	items := make(map[string]map[string]any)
+	var nextID int
+
+	toResource := func(id string) *app.Resource {
+		return &app.Resource{
+			ExternalID:  id,
+			DisplayName: fmt.Sprint(items[id]["name"]),
+			Type:        "item",
+			Properties:  items[id],
+		}
+	}
+
+	rd := app.ResourceDefinition{
+		Type:             "item",
+		DisplayName:      "Item",
+		LifecycleStage:   app.LifecycleStageCode,
+		PropertiesSchema: app.MustParseJSONSchema(itemSchema),
+	}
+	rd.CreateFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		nextID++
+		id := fmt.Sprintf("item-%d", nextID)
+		items[id] = req.Input
+		return &app.OperationResponse{Resource: toResource(id)}, nil
+	}, app.MustParseJSONSchema(itemSchema))
+	rd.ReadFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		if _, ok := items[req.Resource.ExternalID]; !ok {
+			return nil, fmt.Errorf("item %s not found", req.Resource.ExternalID)
+		}
+		return &app.OperationResponse{Resource: toResource(req.Resource.ExternalID)}, nil
+	})
+	rd.DeleteFn(func(ctx context.Context, req *app.OperationRequest) (*app.OperationResponse, error) {
+		res := &app.OperationResponse{Resource: toResource(req.Resource.ExternalID)}
+		delete(items, req.Resource.ExternalID)
+		return res, nil
+	})
+	rd.ListFn(func(ctx context.Context, req *app.ListRequest) (*app.ListResponse, error) {
+		res := &app.ListResponse{}
+		if hideFromList {
+			return res, nil
+		}
+		for id := range items {
+			res.Resources = append(res.Resources, toResource(id))
+		}
+		return res, nil
+	})
+	rd.HealthCheckFn(func(ctx context.Context) (*app.HealthCheckResponse, error) {
+		return &app.HealthCheckResponse{Status: app.HealthCheckStatusDegraded, Message: "slow"}, nil
+	})
+
+	return newClient(t, app.New(app.WithResourceDefinition(rd))), items
+}
+
+func TestRunRoundTrip(t *testing.T) {
+	client, items := newItemApp(t, false)
+
+	findings, err := verify.Run(context.Background(), client, verify.Options{RoundTrip: true})
+	require.NoError(t, err)
+
+	for _, f := range findings {
+		assert.NotEqual(t, verify.SeverityError, f.Severity, "%s: %s", f.Check, f.Message)
+	}
+	assert.Contains(t, findings, verify.Finding{
+		Severity: verify.SeverityWarning,
+		Type:     "item",
+		Check:    verify.CheckHandlers,
+		Message:  "health check status is degraded: slow",
+	})
+	assert.Empty(t, items, "the round trip deletes the resource it created")
+}
+
+func TestRunRoundTripMissingFromList(t *testing.T) {
+	client, _ := newItemApp(t, true)
+
+	findings, err := verify.Run(context.Background(), client, verify.Options{
+		RoundTrip: true,
+		Inputs:    map[string]map[string]any{"item": {"name": "widget", "size": float64(2)}},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, findings, verify.Finding{
+		Severity: verify.SeverityError,
+		Type:     "item",
+		Check:    verify.CheckRoundTrip,
+		Message:  "the created resource item-1 is missing from the list of resources",
+	})
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := verify.ParseSeverity("warning")
+	require.NoError(t, err)
+	assert.Equal(t, verify.SeverityWarning, s)
+
+	_, err = verify.ParseSeverity("fatal")
+	assert.EqualError(t, err, `invalid severity "fatal". Accepted values: info, warning, error`)
+}